@@ -0,0 +1,35 @@
+package anft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/eso-tools/eso-tools/reader"
+	"testing"
+)
+
+var fuzzLimits = reader.Limits{
+	MaxSize:  1 << 20,
+	MaxCount: 1 << 16,
+}
+
+func FuzzParse(f *testing.F) {
+	buf := &bytes.Buffer{}
+	buf.Write(signature)
+	buf.WriteByte(0x01)
+	binary.Write(buf, binary.LittleEndian, []uint32{1, 1, 2, 3, 4})
+	buf.Write(signature)
+
+	f.Add(buf.Bytes())
+	f.Add(signature)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		anftData, err := ParseWithLimits(bytes.NewReader(data), fuzzLimits)
+		if err != nil {
+			return
+		}
+
+		if len(anftData.Animations) != int(anftData.Count) {
+			t.Fatalf("%d animations, count %d", len(anftData.Animations), anftData.Count)
+		}
+	})
+}
@@ -5,7 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	gobinary "github.com/zelenin/go-binary"
+	"github.com/eso-tools/eso-tools/reader"
 	"io"
 )
 
@@ -26,65 +26,76 @@ type Animation struct {
 	Field4 uint32
 }
 
+// Parse parses an ANFT file with reader.DefaultLimits.
 func Parse(r io.Reader) (*AnftData, error) {
+	return ParseWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseWithLimits parses an ANFT file, the number of animations is bounded by limits.
+func ParseWithLimits(r io.Reader, limits reader.Limits) (*AnftData, error) {
 	var (
 		data []byte
 		u32  uint32
 		err  error
 	)
 
-	br := gobinary.NewReader(r, binary.LittleEndian)
+	d := reader.NewDecoder(r, "anft")
 
 	anftData := &AnftData{}
 
-	data, err = br.ReadBytes(int64(len(signature)))
+	data, err = d.ReadBytes("Signature", len(signature))
 	if err != nil {
 		return nil, err
 	}
 	if !bytes.Equal(data, signature) {
-		return nil, errors.New("wrong signature")
+		return nil, d.Error("Signature", errors.New("wrong signature"))
 	}
 	anftData.Signature = data
 
-	u8, err := br.ReadUint8()
+	u8, err := d.ReadUint8("Version")
 	if err != nil {
 		return nil, err
 	}
 	if u8 != 0x01 {
-		return nil, fmt.Errorf("unsupported version: %d", u8)
+		return nil, d.Error("Version", fmt.Errorf("unsupported version: %d", u8))
 	}
 	anftData.Version = u8
 
-	u32, err = br.ReadUint32()
+	u32, err = d.ReadUint32("Count", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	anftData.Count = u32
 
+	err = limits.CheckCount(uint64(anftData.Count))
+	if err != nil {
+		return nil, d.Error("Count", err)
+	}
+
 	anftData.Animations = make([]*Animation, anftData.Count)
 
 	for i := 0; i < int(anftData.Count); i++ {
 		datum := &Animation{}
 
-		u32, err = br.ReadUint32()
+		u32, err = d.ReadUint32("Animation.Field1", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		datum.Field1 = u32
 
-		u32, err = br.ReadUint32()
+		u32, err = d.ReadUint32("Animation.Field2", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		datum.Field2 = u32
 
-		u32, err = br.ReadUint32()
+		u32, err = d.ReadUint32("Animation.FileId", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		datum.FileId = u32
 
-		u32, err = br.ReadUint32()
+		u32, err = d.ReadUint32("Animation.Field4", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
@@ -93,12 +104,12 @@ func Parse(r io.Reader) (*AnftData, error) {
 		anftData.Animations[i] = datum
 	}
 
-	data, err = br.ReadBytes(int64(len(signature)))
+	data, err = d.ReadBytes("EndSignature", len(signature))
 	if err != nil {
 		return nil, err
 	}
 	if !bytes.Equal(data, signature) {
-		return nil, errors.New("wrong end signature")
+		return nil, d.Error("EndSignature", errors.New("wrong end signature"))
 	}
 	anftData.EndSignature = data
 
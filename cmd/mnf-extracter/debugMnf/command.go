@@ -1,9 +1,9 @@
 package debugMnf
 
 import (
-	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/eso-tools/eso-tools/extracter"
 	"github.com/eso-tools/eso-tools/format"
@@ -15,8 +15,9 @@ import (
 )
 
 type Config struct {
-	Input  string `long:"input" short:"i" required:"true"`
-	Output string `long:"output" short:"o" required:"true"`
+	Input   string `long:"input" short:"i" required:"true"`
+	Output  string `long:"output" short:"o" required:"true"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
 }
 
 func Command(ctx context.Context, args []string) error {
@@ -40,14 +41,16 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("'%s' is not a file", inputFilePath)
 	}
 
-	mnfData, err := mnf.Parse(inputFilePath)
+	flavour, err := mnf.ParseFlavour(config.Flavour)
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
 	}
 
-	if len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records) {
-		return fmt.Errorf("len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records)")
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, mnf.WithFlavour(flavour))
+	if err != nil {
+		return fmt.Errorf("mnf.ParseContext: %s", err)
 	}
+	defer mnfData.Close()
 
 	f, err := os.Create(config.Output)
 	if err != nil {
@@ -55,18 +58,16 @@ func Command(ctx context.Context, args []string) error {
 	}
 	defer f.Close()
 
-	zosftData, err := mnfData.GetZosft()
-	if err != nil {
-		return fmt.Errorf("mnfData.GetZosft: %s", err)
-	}
+	// the file names of Records, the leading records of a depot have none
+	fileNames := map[int]string{}
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			return fmt.Errorf("mnfData.Records: %s", err)
+		}
 
-	fileNames := map[uint32]string{}
-	if zosftData != nil {
-		fileNames = zosftData.GetFileNamesById()
+		fileNames[entry.Index] = entry.FileName
 	}
 
-	twoZeroBytes := []byte{0x00, 0x00}
-
 	log.Printf("Writing \"%s\"...", config.Output)
 
 	csvWriter := csv.NewWriter(f)
@@ -78,7 +79,7 @@ func Command(ctx context.Context, args []string) error {
 		"",
 
 		"Id",
-		"ItemId",
+		"Field2",
 		"Flags",
 
 		"",
@@ -91,7 +92,9 @@ func Command(ctx context.Context, args []string) error {
 		"ArchiveIndex",
 		"ArchiveBasedIndex",
 		"UniqueId",
+		"LikelyLoaded",
 		"CompressionType",
+		"Decompressor",
 
 		"",
 
@@ -105,53 +108,25 @@ func Command(ctx context.Context, args []string) error {
 		indexes[i] = 0
 	}
 
-	uniqueIds := map[uint16]map[string]int64{}
-	for i := uint16(0); i < mnfData.ArchiveCount; i++ {
-		uniqueIds[i] = map[string]int64{}
-	}
-
-	for i, _ := range mnfData.Index3.Block2Records {
-		block2Record := mnfData.Index3.Block2Records[i]
-		block3Record := mnfData.Index3.Block3Records[i]
-
-		id := fmt.Sprintf("%d-%d-%d-%d-%d", block2Record.Id, block2Record.Field2[0], block2Record.Field2[1], block2Record.Flags[0], block2Record.Flags[1])
-
-		uniqueIds[block3Record.ArchiveIndex][id]++
-	}
-
-	isDepot := mnfData.IsDepot()
-	skip := isDepot
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		block2Record := mnfData.Index3.Block2Record(i)
+		block3Record := mnfData.Index3.Block3Record(i)
 
-	for i, _ := range mnfData.Index3.Block2Records {
-		block2Record := mnfData.Index3.Block2Records[i]
-		block3Record := mnfData.Index3.Block3Records[i]
-
-		if isDepot && skip && block3Record.ArchiveIndex != 0 {
-			skip = false
+		archive, err := mnfData.GetArchive(block3Record.ArchiveIndex)
+		if errors.Is(err, mnf.ErrorMissingArchive) {
+			continue
 		}
-
-		archive, ok := mnfData.Archives[block3Record.ArchiveIndex]
-		if !ok {
-			return fmt.Errorf("not valid archiveIndex: %d", block3Record.ArchiveIndex)
+		if err != nil {
+			return fmt.Errorf("mnfData.GetArchive: %s", err)
 		}
 		if !archive.IsValid(block3Record) {
 			continue
 		}
 
-		var fileName string
-
-		_, ok = fileNames[block2Record.Id]
-		if ok {
-			if bytes.Equal(twoZeroBytes, block2Record.Field2) && !skip {
-				fileName = fileNames[block2Record.Id]
-				delete(fileNames, block2Record.Id)
-			}
-		}
-
 		var byte10 = []byte("")
-		data, err := mnfData.Read(block3Record)
+		data, decompressor, err := mnfData.ReadWithDecompressor(block3Record)
 		if err != nil {
-			return fmt.Errorf("mnfData.read: %s", err)
+			return fmt.Errorf("mnfData.ReadWithDecompressor: %s", err)
 		}
 
 		if len(data) < 10 {
@@ -163,10 +138,20 @@ func Command(ctx context.Context, args []string) error {
 		ext := extracter.GetExtension(byte10)
 		indexes[block3Record.ArchiveIndex]++
 
-		var unique bool
-		id := fmt.Sprintf("%d-%d-%d-%d-%d", block2Record.Id, block2Record.Field2[0], block2Record.Field2[1], block2Record.Flags[0], block2Record.Flags[1])
-		if uniqueIds[block3Record.ArchiveIndex][id] == 1 {
-			unique = true
+		unique := true
+		var loaded bool
+		variants := mnfData.Variants(block2Record.Id)
+		for _, variant := range variants {
+			if variant.Index != i {
+				continue
+			}
+
+			loaded = variant.LikelyLoaded
+			for _, duplicate := range variants.GetDuplicates(variant) {
+				if duplicate.Record3.ArchiveIndex == block3Record.ArchiveIndex {
+					unique = false
+				}
+			}
 		}
 
 		csvWriter.Write([]string{
@@ -184,15 +169,17 @@ func Command(ctx context.Context, args []string) error {
 			fmt.Sprintf("%d", block3Record.CompressedSize),
 			fmt.Sprintf("0x%08x", block3Record.Hash),
 			fmt.Sprintf("%d", block3Record.Offset),
-			fmt.Sprintf("%d", block3Record.Offset+block3Record.CompressedSize),
+			fmt.Sprintf("%d", block3Record.End()),
 			fmt.Sprintf("%d", block3Record.ArchiveIndex),
 			fmt.Sprintf("%d", indexes[block3Record.ArchiveIndex]),
 			fmt.Sprintf("%t", unique),
+			fmt.Sprintf("%t", loaded),
 			fmt.Sprintf("%d", block3Record.CompressionType),
+			decompressor.Name,
 
 			"",
 
-			fmt.Sprintf("%s", fileName),
+			fmt.Sprintf("%s", fileNames[i]),
 			fmt.Sprintf("%s", ext),
 			fmt.Sprintf("%s", format.BytesFormat(byte10)),
 		})
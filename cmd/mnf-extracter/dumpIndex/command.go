@@ -1,7 +1,6 @@
 package dumpIndex
 
 import (
-	"bytes"
 	"context"
 	"encoding/csv"
 	"fmt"
@@ -14,12 +13,11 @@ import (
 )
 
 type Config struct {
-	Input  string `long:"input" short:"i" required:"true"`
-	Output string `long:"output" short:"o" required:"true"`
+	Input   string `long:"input" short:"i" required:"true"`
+	Output  string `long:"output" short:"o" required:"true"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
 }
 
-var twoZeroBytes = []byte{0x00, 0x00}
-
 func Command(ctx context.Context, args []string) error {
 	var config Config
 	_, err := flags.ParseArgs(&config, args[1:])
@@ -41,23 +39,19 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("'%s' is not a file", inputFilePath)
 	}
 
-	mnfData, err := mnf.Parse(inputFilePath)
+	flavour, err := mnf.ParseFlavour(config.Flavour)
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
 	}
 
-	zosftData, err := mnfData.GetZosft()
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, mnf.WithFlavour(flavour))
 	if err != nil {
-		log.Fatalf("mnfData.GetZosft: %s", err)
-	}
-
-	fileNames := map[uint32]string{}
-	if zosftData != nil {
-		fileNames = zosftData.GetFileNamesById()
+		return fmt.Errorf("mnf.ParseContext: %s", err)
 	}
+	defer mnfData.Close()
 
-	if len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records) {
-		return fmt.Errorf("len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records)")
+	if mnfData.Index3.Count2 != mnfData.Index3.Count3 {
+		return fmt.Errorf("mnfData.Index3.Count2 != mnfData.Index3.Count3")
 	}
 
 	err = os.MkdirAll(filepath.Dir(config.Output), 0777)
@@ -85,39 +79,17 @@ func Command(ctx context.Context, args []string) error {
 		"fileName",
 	})
 
-	isDepot := mnfData.IsDepot()
-	skip := isDepot
-
-	for i := 0; i < len(mnfData.Index3.Block2Records); i++ {
-		record := &extracter.Record{
-			Record2: mnfData.Index3.Block2Records[i],
-			Record3: mnfData.Index3.Block3Records[i],
-		}
-
-		if isDepot && skip && record.Record3.ArchiveIndex != 0 {
-			skip = false
-		}
-
-		if skip {
-			continue
-		}
-
-		archive, ok := mnfData.Archives[record.Record3.ArchiveIndex]
-		if !ok {
-			log.Fatalf("not valid archiveIndex: %d", record.Record3.ArchiveIndex)
+	// the records that cannot be read are listed too, they are in the index
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			return fmt.Errorf("mnfData.Records: %s", err)
 		}
 
-		if !archive.IsValid(record.Record3) {
-			continue
+		if ctx.Err() != nil {
+			break
 		}
 
-		_, ok = fileNames[record.Record2.Id]
-		if ok {
-			if bytes.Equal(twoZeroBytes, record.Record2.Field2) {
-				record.FileName = fileNames[record.Record2.Id]
-				delete(fileNames, record.Record2.Id)
-			}
-		}
+		record := &extracter.Record{Entry: entry}
 
 		//if record.Record3.ArchiveIndex != 0 {
 		//	continue
@@ -136,5 +108,10 @@ func Command(ctx context.Context, args []string) error {
 
 	csvWriter.Flush()
 
+	err = ctx.Err()
+	if err != nil {
+		return fmt.Errorf("dump stopped: %s", err)
+	}
+
 	return nil
 }
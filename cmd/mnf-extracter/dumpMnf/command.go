@@ -13,8 +13,9 @@ import (
 )
 
 type Config struct {
-	Input  string `long:"input" short:"i" required:"true"`
-	Output string `long:"output" short:"o" required:"true"`
+	Input   string `long:"input" short:"i" required:"true"`
+	Output  string `long:"output" short:"o" required:"true"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
 }
 
 func Command(ctx context.Context, args []string) error {
@@ -38,13 +39,23 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("'%s' is not a file", inputFilePath)
 	}
 
-	mnfData, err := mnf.Parse(inputFilePath)
+	flavour, err := mnf.ParseFlavour(config.Flavour)
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
 	}
 
-	if len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records) {
-		return fmt.Errorf("len(mnfData.Index3.Block2Records) != len(mnfData.Index3.Block3Records)")
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, mnf.WithFlavour(flavour))
+	if err != nil {
+		return fmt.Errorf("mnf.ParseContext: %s", err)
+	}
+	defer mnfData.Close()
+
+	for _, warning := range mnfData.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	if mnfData.Index3.Count2 != mnfData.Index3.Count3 {
+		return fmt.Errorf("mnfData.Index3.Count2 != mnfData.Index3.Count3")
 	}
 
 	err = os.MkdirAll(filepath.Dir(config.Output), 0777)
@@ -69,7 +80,7 @@ func Command(ctx context.Context, args []string) error {
 		"",
 
 		"Id",
-		"ItemId",
+		"Field2",
 		"Flags",
 
 		"",
@@ -83,9 +94,9 @@ func Command(ctx context.Context, args []string) error {
 		"FileName",
 	})
 
-	for i, _ := range mnfData.Index3.Block2Records {
-		block2Record := mnfData.Index3.Block2Records[i]
-		block3Record := mnfData.Index3.Block3Records[i]
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		block2Record := mnfData.Index3.Block2Record(i)
+		block3Record := mnfData.Index3.Block3Record(i)
 
 		csvWriter.Write([]string{
 			fmt.Sprintf("%d", i),
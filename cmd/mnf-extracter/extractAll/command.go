@@ -1,7 +1,6 @@
 package extractAll
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha1"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync/atomic"
 
 	"github.com/eso-tools/eso-tools/extracter"
 	"github.com/eso-tools/eso-tools/mnf"
@@ -39,6 +39,11 @@ type Config struct {
 	Threads      uint8  `long:"threads" short:"t"`
 	HashSumFile  string `long:"hashSumFile" short:"h"`
 	ConvertDdsTo string `long:"convert-dds-to"`
+	Mmap         bool   `long:"mmap"`
+	KeepHeaders  bool   `long:"keep-headers"`
+	Flavour      string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
+	MaxErrors    int    `long:"max-errors"`
+	ErrorReport  string `long:"error-report"`
 }
 
 func Command(ctx context.Context, args []string) error {
@@ -88,7 +93,7 @@ func Command(ctx context.Context, args []string) error {
 	}
 
 	if config.ConvertDdsTo != "" && !supportedFormatsForDdsConverting[config.ConvertDdsTo] {
-		log.Fatalf("Unsupported format for converting: %s", config.ConvertDdsTo)
+		return fmt.Errorf("unsupported format for converting: %s", config.ConvertDdsTo)
 	}
 
 	err = os.MkdirAll(outputDirPath, 0755)
@@ -96,11 +101,27 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("MkdirAll: %s", err)
 	}
 
+	flavour, err := mnf.ParseFlavour(config.Flavour)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
+	}
+
+	options := []mnf.Option{
+		mnf.WithFlavour(flavour),
+	}
+	if config.Mmap {
+		options = append(options, mnf.WithMmap())
+	}
+	if config.KeepHeaders {
+		options = append(options, mnf.WithHeaders())
+	}
+
 	log.Printf("Parsing %q...", inputFilePath)
-	mnfData, err := mnf.Parse(inputFilePath)
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, options...)
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseContext: %s", err)
 	}
+	defer mnfData.Close()
 
 	pool = workerpool.NewPool(int64(threads), 1000)
 
@@ -119,218 +140,73 @@ func Command(ctx context.Context, args []string) error {
 
 	log.Printf("Prepare records...")
 
-	addTask := func(id int64, total int, file *extracter.Record, mnfData *mnf.Mnf) {
-		pool.AddTask(func(ctx context.Context) error {
-			if id%10000 == 0 {
-				log.Printf("Task %d/%d", id, total)
-			}
+	var extracted atomic.Int64
 
-			data, err := mnfData.Read(file.Record3)
-			if err != nil {
-				log.Printf("mnfData.Read: %s", err)
-				return err
-			}
+	report := extracter.NewReport(config.MaxErrors)
 
-			file.Data = data
+	// extractCtx is cancelled by ctx and when --max-errors is reached
+	extractCtx, stop := context.WithCancel(ctx)
+	defer stop()
 
-			fpath := filepath.Join(outputDirPath, fmt.Sprintf("%03d", file.Record3.ArchiveIndex), file.GetRawFilename())
-			err = os.MkdirAll(filepath.Dir(fpath), 0755)
-			if err != nil {
-				log.Fatalf("os.MkdirAll: %s", err)
-			}
+	extraction := &extraction{
+		mnfData:       mnfData,
+		outputDirPath: outputDirPath,
+		convertDdsTo:  config.ConvertDdsTo,
+		hashRegistry:  hashRegistry,
+	}
 
-			dest, err := os.Create(fpath)
-			if err != nil {
-				log.Fatalf("os.Create: %s", err)
+	addTask := func(id int64, total int, file *extracter.Record) {
+		pool.AddTask(func(context.Context) error {
+			// the queued records are dropped on cancellation, the records being written are finished so no file is
+			// left half-written
+			if extractCtx.Err() != nil {
+				return nil
 			}
 
-			dataReader := bytes.NewReader(data)
-
-			var hashSum []byte
-
-			if hashSumFilePath == "" {
-				reader := dataReader
-
-				_, err = io.Copy(dest, reader)
-				if err != nil {
-					log.Fatalf("io.Copy: %s", err)
-				}
-			} else {
-				hasher := sha1.New()
-				reader := io.TeeReader(dataReader, hasher)
-
-				_, err = io.Copy(dest, reader)
-				if err != nil {
-					log.Fatalf("io.Copy: %s", err)
-				}
-
-				hashSum = hasher.Sum(nil)
-
-				hashRegistry.Add(filepath.ToSlash(filepath.Join(fmt.Sprintf("%03d", file.Record3.ArchiveIndex), file.GetRawFilename())), hashSum)
+			if id%10000 == 0 {
+				log.Printf("Task %d/%d", id, total)
 			}
 
-			dest.Close()
-
-			if config.ConvertDdsTo != "" && filepath.Ext(fpath) == ".dds" {
-				ddsPath := fpath
-				// texconv does not accept absolute linux paths
-				if runtime.GOOS == "linux" {
-					curDir, err := os.Getwd()
-					if err != nil {
-						return err
-					}
-
-					relPath, err := filepath.Rel(curDir, fpath)
-					if err != nil {
-						return err
-					}
-					ddsPath = relPath
-				}
-
-				args := []string{
-					"-ft",
-					config.ConvertDdsTo,
-					//"-f",
-					//"R8G8B8A8_UNORM_SRGB",
-					"-y",
-					"-o",
-					filepath.Dir(ddsPath),
-					ddsPath,
+			stage, err := extraction.extract(file)
+			if err != nil {
+				log.Printf("%s: %s: %s", file.GetRawId(), stage, err)
+				if report.Add(file, stage, err) {
+					stop()
 				}
 
-				_, err := texconv.Texconv(args, false, true, true)
-				if err == nil {
-					os.Remove(fpath)
-					if hashSumFilePath != "" {
-						hasher := sha1.New()
-						f, err := os.Open(strings.TrimSuffix(fpath, ".dds") + "." + config.ConvertDdsTo)
-						if err != nil {
-							return err
-						}
-						_, err = io.Copy(hasher, f)
-						if err != nil {
-							return err
-						}
-
-						f.Close()
-
-						hashRegistry.Add(filepath.ToSlash(strings.TrimSuffix(filepath.Join(fmt.Sprintf("%03d", file.Record3.ArchiveIndex), file.GetRawFilename()), ".dds")+"."+config.ConvertDdsTo), hasher.Sum(nil))
-						hashRegistry.Remove(filepath.ToSlash(filepath.Join(fmt.Sprintf("%03d", file.Record3.ArchiveIndex), file.GetRawFilename())))
-					}
-				}
+				return nil
 			}
 
-			if file.FileName != "" {
-				fpath = filepath.Join(outputDirPath, file.FileName)
-				err = os.MkdirAll(filepath.Dir(fpath), 0755)
-				if err != nil {
-					log.Fatalf("os.MkdirAll: %s", err)
-				}
-
-				dest, err := os.Create(fpath)
-				if err != nil {
-					log.Fatalf("os.Create: %s", err)
-				}
-
-				_, err = dataReader.Seek(0, io.SeekStart)
-				if err != nil {
-					log.Fatalf("dataReader.Seek: %s", err)
-				}
-
-				_, err = io.Copy(dest, dataReader)
-				if err != nil {
-					log.Fatalf("io.Copy: %s", err)
-				}
-
-				if hashSum != nil {
-					hashRegistry.Add(filepath.ToSlash(file.FileName), hashSum)
-				}
-
-				dest.Close()
-
-				if config.ConvertDdsTo != "" && filepath.Ext(fpath) == ".dds" {
-					ddsPath := fpath
-					// texconv does not accept absolute linux paths
-					if runtime.GOOS == "linux" {
-						curDir, err := os.Getwd()
-						if err != nil {
-							return err
-						}
-
-						relPath, err := filepath.Rel(curDir, fpath)
-						if err != nil {
-							return err
-						}
-						ddsPath = relPath
-					}
-
-					args := []string{
-						"-ft",
-						config.ConvertDdsTo,
-						//"-f",
-						//"R8G8B8A8_UNORM_SRGB",
-						"-y",
-						"-o",
-						filepath.Dir(ddsPath),
-						ddsPath,
-					}
-
-					_, err := texconv.Texconv(args, false, true, true)
-					if err == nil {
-						os.Remove(fpath)
-						if hashSumFilePath != "" {
-							hasher := sha1.New()
-							f, err := os.Open(strings.TrimSuffix(fpath, ".dds") + "." + config.ConvertDdsTo)
-							if err != nil {
-								return err
-							}
-							_, err = io.Copy(hasher, f)
-							if err != nil {
-								return err
-							}
-
-							f.Close()
-
-							hashRegistry.Add(filepath.ToSlash(strings.TrimSuffix(file.FileName, ".dds")+"."+config.ConvertDdsTo), hasher.Sum(nil))
-							hashRegistry.Remove(filepath.ToSlash(file.FileName))
-						}
-					}
-				}
-			}
+			extracted.Add(1)
 
 			return nil
 		})
 	}
 
-	recordChan := make(chan *extracter.Record, 100)
-	errorChan := make(chan error, 1)
-
-	go func() {
-		extracter.CombineRecords(mnfData, recordChan, errorChan)
-	}()
-
 	log.Printf("Extracting...")
 
 	var i int64
-Loop:
-	for {
-		select {
-		case record, ok := <-recordChan:
-			if !ok {
-				break Loop
-			}
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			return fmt.Errorf("mnfData.Records: %s", err)
+		}
 
-			addTask(i+1, int(mnfData.Index3.Count3), record, mnfData)
-			i++
+		if extractCtx.Err() != nil {
+			break
+		}
 
-		case err, ok := <-errorChan:
-			if ok {
-				return fmt.Errorf("extracter.CombineRecords: %s", err)
+		if err != nil {
+			file := &extracter.Record{Entry: entry}
+			log.Printf("%s: archive: %s", file.GetRawId(), err)
+			if report.Add(file, "archive", err) {
+				stop()
 			}
-
-		default:
-
+			i++
+			continue
 		}
+
+		addTask(i+1, int(mnfData.Index3.Count3), &extracter.Record{Entry: entry})
+		i++
 	}
 
 	pool.Wait()
@@ -359,5 +235,145 @@ Loop:
 
 	log.Printf("PeakMemory: %0.1fMb Duration: %s", float64(pr.GetPeakMemory())/1024/1024, pr.GetDuration().String())
 
-	return nil
+	if config.ErrorReport != "" {
+		log.Printf("Writing %s", config.ErrorReport)
+
+		err = writeErrorReport(config.ErrorReport, report)
+		if err != nil {
+			return fmt.Errorf("writeErrorReport: %s", err)
+		}
+	}
+
+	log.Printf("Extracted %d records, %d failed", extracted.Load(), len(report.Failures()))
+
+	err = ctx.Err()
+	if err != nil {
+		return fmt.Errorf("extraction stopped after %d records: %s", extracted.Load(), err)
+	}
+
+	return report.Err()
+}
+
+func writeErrorReport(path string, report *extracter.Report) error {
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = report.WriteCsv(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+type extraction struct {
+	mnfData       *mnf.Mnf
+	outputDirPath string
+	convertDdsTo  string
+	hashRegistry  *hash.Registry
+}
+
+// extract writes the files of record and converts its .dds files, it returns the stage that failed. The copy is not
+// stopped by cancellation, so no file is left half-written. The hash of each file is added to the hash registry.
+func (extraction *extraction) extract(file *extracter.Record) (string, error) {
+	hasher := sha1.New()
+	var w io.Writer
+	if extraction.hashRegistry != nil {
+		w = hasher
+	}
+
+	names, stage, err := extracter.Extract(context.Background(), extraction.mnfData, file, extraction.outputDirPath, w)
+	if err != nil {
+		return stage, err
+	}
+
+	if extraction.hashRegistry != nil {
+		sum := hasher.Sum(nil)
+		for _, name := range names {
+			extraction.hashRegistry.Add(filepath.ToSlash(name), sum)
+		}
+	}
+
+	for _, name := range names {
+		stage, err = extraction.convertDds(name)
+		if err != nil {
+			return stage, err
+		}
+	}
+
+	return "", nil
+}
+
+// convertDds replaces the .dds file name of the output directory with a file of the --convert-dds-to format, the
+// .dds file is kept when texconv fails.
+func (extraction *extraction) convertDds(name string) (string, error) {
+	if extraction.convertDdsTo == "" || filepath.Ext(name) != ".dds" {
+		return "", nil
+	}
+
+	fpath := filepath.Join(extraction.outputDirPath, name)
+
+	ddsPath := fpath
+	// texconv does not accept absolute linux paths
+	if runtime.GOOS == "linux" {
+		curDir, err := os.Getwd()
+		if err != nil {
+			return "convert", err
+		}
+
+		relPath, err := filepath.Rel(curDir, fpath)
+		if err != nil {
+			return "convert", err
+		}
+		ddsPath = relPath
+	}
+
+	args := []string{
+		"-ft",
+		extraction.convertDdsTo,
+		//"-f",
+		//"R8G8B8A8_UNORM_SRGB",
+		"-y",
+		"-o",
+		filepath.Dir(ddsPath),
+		ddsPath,
+	}
+
+	_, err := texconv.Texconv(args, false, true, true)
+	if err != nil {
+		return "convert", err
+	}
+
+	os.Remove(fpath)
+
+	if extraction.hashRegistry == nil {
+		return "", nil
+	}
+
+	convertedName := strings.TrimSuffix(name, ".dds") + "." + extraction.convertDdsTo
+
+	f, err := os.Open(filepath.Join(extraction.outputDirPath, convertedName))
+	if err != nil {
+		return "hash", err
+	}
+	defer f.Close()
+
+	hasher := sha1.New()
+	_, err = io.Copy(hasher, f)
+	if err != nil {
+		return "hash", err
+	}
+
+	extraction.hashRegistry.Add(filepath.ToSlash(convertedName), hasher.Sum(nil))
+	extraction.hashRegistry.Remove(filepath.ToSlash(name))
+
+	return "", nil
 }
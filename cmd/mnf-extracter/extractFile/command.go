@@ -1,10 +1,8 @@
 package extractFile
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,6 +25,7 @@ type Config struct {
 	Output  string `long:"output" short:"o" required:"true"`
 	Id      string `long:"id" required:"true"`
 	Threads uint8  `long:"threads" short:"t"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
 }
 
 var re = regexp.MustCompile(`(?i)^(0x)?([0-9a-f]{8})-([0-9a-f]{8})`)
@@ -81,11 +80,17 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("MkdirAll: %s", err)
 	}
 
+	flavour, err := mnf.ParseFlavour(config.Flavour)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
+	}
+
 	log.Printf("Parsing %q...", inputFilePath)
-	mnfData, err := mnf.Parse(inputFilePath)
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, mnf.WithFlavour(flavour))
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseContext: %s", err)
 	}
+	defer mnfData.Close()
 
 	pool = workerpool.NewPool(int64(threads), 1000)
 
@@ -104,107 +109,52 @@ func Command(ctx context.Context, args []string) error {
 
 	log.Printf("Prepare records...")
 
-	addTask := func(id int64, total int, file *extracter.Record, mnfData *mnf.Mnf) {
-		pool.AddTask(func(ctx context.Context) error {
-			if id%10000 == 0 {
-				log.Printf("Task %d/%d", id, total)
-			}
-
-			data, err := mnfData.Read(file.Record3)
-			if err != nil {
-				log.Fatalf("mnfData.Read: %s", err)
-			}
-
-			file.Data = data
+	report := extracter.NewReport(0)
 
-			fpath := filepath.Join(outputDirPath, fmt.Sprintf("%03d", file.Record3.ArchiveIndex), file.GetRawFilename())
-			err = os.MkdirAll(filepath.Dir(fpath), 0755)
-			if err != nil {
-				log.Fatalf("os.MkdirAll: %s", err)
+	addTask := func(id int64, total int, file *extracter.Record, mnfData *mnf.Mnf) {
+		pool.AddTask(func(context.Context) error {
+			if ctx.Err() != nil {
+				return nil
 			}
 
-			dest, err := os.Create(fpath)
-			if err != nil {
-				log.Fatalf("os.Create: %s", err)
+			if id%10000 == 0 {
+				log.Printf("Task %d/%d", id, total)
 			}
 
-			reader := bytes.NewReader(data)
-
-			_, err = io.Copy(dest, reader)
+			_, stage, err := extracter.Extract(ctx, mnfData, file, outputDirPath, nil)
 			if err != nil {
-				log.Fatalf("io.Copy: %s", err)
-			}
-
-			dest.Close()
-
-			if file.FileName != "" {
-				fpath = filepath.Join(outputDirPath, file.FileName)
-				err = os.MkdirAll(filepath.Dir(fpath), 0755)
-				if err != nil {
-					log.Fatalf("os.MkdirAll: %s", err)
-				}
-
-				dest, err := os.Create(fpath)
-				if err != nil {
-					log.Fatalf("os.Create: %s", err)
-				}
-
-				_, err = reader.Seek(0, io.SeekStart)
-				if err != nil {
-					log.Fatalf("dataReader.Seek: %s", err)
-				}
-
-				_, err = io.Copy(dest, reader)
-				if err != nil {
-					log.Fatalf("io.Copy: %s", err)
-				}
-
-				dest.Close()
+				log.Printf("%s: %s: %s", file.GetRawId(), stage, err)
+				report.Add(file, stage, err)
 			}
 
 			return nil
 		})
 	}
 
-	recordChan := make(chan *extracter.Record, 100)
-	errorChan := make(chan error, 1)
-
-	go func() {
-		extracter.CombineRecords(mnfData, recordChan, errorChan)
-	}()
-
 	log.Printf("Searching...")
 
-	var i int64
-Loop:
-	for {
-		select {
-		case record, ok := <-recordChan:
-			if !ok {
-				break Loop
-			}
-
-			if record.Record2.Id == searchRecord.Id && bytes.Equal(record.Record2.Field2, searchRecord.Field2) && bytes.Equal(record.Record2.Flags, searchRecord.Flags) {
-				log.Printf("Extracting...")
-				addTask(i+1, int(mnfData.Index3.Count3), record, mnfData)
-				break Loop
-			}
+	record, ok, err := extracter.Lookup(mnfData, searchRecord.Id, searchRecord.Field2, searchRecord.Flags)
+	if err != nil {
+		pool.Wait()
+		return fmt.Errorf("extracter.Lookup: %s", err)
+	}
 
-			i++
+	if !ok {
+		pool.Wait()
+		return fmt.Errorf("record %s not found", config.Id)
+	}
 
-		case err, ok := <-errorChan:
-			if ok {
-				return fmt.Errorf("extracter.CombineRecords: %s", err)
-			}
+	log.Printf("Extracting...")
+	addTask(1, 1, record, mnfData)
 
-		default:
+	pool.Wait()
 
-		}
+	err = ctx.Err()
+	if err != nil {
+		return fmt.Errorf("extraction stopped: %s", err)
 	}
 
-	pool.Wait()
-
 	log.Printf("PeakMemory: %0.1fMb Duration: %s", float64(pr.GetPeakMemory())/1024/1024, pr.GetDuration().String())
 
-	return nil
+	return report.Err()
 }
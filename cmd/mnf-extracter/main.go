@@ -1,40 +1,82 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/debugMnf"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/dumpIndex"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/dumpMnf"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/extractAll"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/extractFile"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/parseLng"
+	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/replaceFile"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/testZosft"
+	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/verifyMnf"
 	"github.com/eso-tools/eso-tools/cmd/mnf-extracter/writeLng"
-	"github.com/new-world-tools/go-oodle"
 	go_app "github.com/zelenin/go-app"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-func main() {
-	if !oodle.IsDllExist() {
-		err := oodle.Download()
-		if err != nil {
-			log.Fatalf("no oo2core library")
-		}
-	}
+// exitCancelled is the exit code of a command stopped by Ctrl+C, a failed command exits with 1.
+const exitCancelled = 130
+
+var errCancelled = errors.New("cancelled")
 
+func main() {
 	app := go_app.NewApp()
 
-	app.AddHandler(go_app.CommandChecker("testZosft"), testZosft.Command)
-	app.AddHandler(go_app.CommandChecker("dumpMnf"), dumpMnf.Command)
-	app.AddHandler(go_app.CommandChecker("dumpIndex"), dumpIndex.Command)
-	app.AddHandler(go_app.CommandChecker("debugMnf"), debugMnf.Command)
-	app.AddHandler(go_app.CommandChecker("extractAll"), extractAll.Command)
-	app.AddHandler(go_app.CommandChecker("extractFile"), extractFile.Command)
-	app.AddHandler(go_app.CommandChecker("parseLng"), parseLng.Command)
-	app.AddHandler(go_app.CommandChecker("writeLng"), writeLng.Command)
+	app.AddHandler(go_app.CommandChecker("testZosft"), withInterrupt(testZosft.Command))
+	app.AddHandler(go_app.CommandChecker("dumpMnf"), withInterrupt(dumpMnf.Command))
+	app.AddHandler(go_app.CommandChecker("dumpIndex"), withInterrupt(dumpIndex.Command))
+	app.AddHandler(go_app.CommandChecker("debugMnf"), withInterrupt(debugMnf.Command))
+	app.AddHandler(go_app.CommandChecker("verifyMnf"), withInterrupt(verifyMnf.Command))
+	app.AddHandler(go_app.CommandChecker("extractAll"), withInterrupt(extractAll.Command))
+	app.AddHandler(go_app.CommandChecker("extractFile"), withInterrupt(extractFile.Command))
+	app.AddHandler(go_app.CommandChecker("replaceFile"), withInterrupt(replaceFile.Command))
+	app.AddHandler(go_app.CommandChecker("parseLng"), withInterrupt(parseLng.Command))
+	app.AddHandler(go_app.CommandChecker("writeLng"), withInterrupt(writeLng.Command))
 
 	err := app.Run()
+	if errors.Is(err, errCancelled) {
+		log.Printf("app.Run: %s", err)
+		os.Exit(exitCancelled)
+	}
 	if err != nil {
 		log.Fatalf("app.Run: %s", err)
 	}
 }
+
+// withInterrupt cancels the context of handler on the first Ctrl+C so it can stop cleanly, the next one terminates the
+// process. The error of a cancelled handler is errCancelled.
+func withInterrupt(handler go_app.Handler) go_app.Handler {
+	return func(ctx context.Context, args []string) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		// the goroutine ends with the handler, ctx is cancelled on return
+		go func() {
+			select {
+			case <-signals:
+				log.Printf("Cancelling, interrupt again to quit...")
+				signal.Stop(signals)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := handler(ctx, args)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("%w: %s", errCancelled, err)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,113 @@
+package replaceFile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/eso-tools/eso-tools/mnf"
+	"github.com/jessevdk/go-flags"
+)
+
+type Config struct {
+	Input    string `long:"input" short:"i" required:"true"`
+	Id       string `long:"id"`
+	File     string `long:"file" short:"f"`
+	Rollback bool   `long:"rollback"`
+	Flavour  string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
+	Hash     string `long:"hash"`
+}
+
+var re = regexp.MustCompile(`(?i)^(0x)?([0-9a-f]{8})-([0-9a-f]{8})`)
+
+func Command(ctx context.Context, args []string) error {
+	var config Config
+	_, err := flags.ParseArgs(&config, args[1:])
+	if err != nil {
+		return nil
+	}
+
+	var searchRecord mnf.Block2Record
+
+	inputFilePath, err := filepath.Abs(filepath.Clean(config.Input))
+	if err != nil {
+		return fmt.Errorf("filepath.Abs: %s", err)
+	}
+
+	inputFileInfo, err := os.Stat(inputFilePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("'%s' does not exist", inputFilePath)
+	}
+
+	if inputFileInfo.IsDir() {
+		return fmt.Errorf("'%s' is not a file", inputFilePath)
+	}
+
+	log.Printf("Checking journal...")
+	err = mnf.Rollback(inputFilePath)
+	if err != nil {
+		return fmt.Errorf("mnf.Rollback: %s", err)
+	}
+
+	if config.Rollback {
+		return nil
+	}
+
+	if config.Id == "" || config.File == "" {
+		return fmt.Errorf("--id and --file are required")
+	}
+
+	matches := re.FindStringSubmatch(config.Id)
+	if matches == nil {
+		return fmt.Errorf("Invalid id: %s", config.Id)
+	}
+
+	fmt.Sscanf(matches[2], `%08x`, &searchRecord.Id)
+	fmt.Sscanf(matches[3], `%04x%04x`, &searchRecord.Field2, &searchRecord.Flags)
+
+	data, err := os.ReadFile(config.File)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile: %s", err)
+	}
+
+	flavour, err := mnf.ParseFlavour(config.Flavour)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
+	}
+
+	options := []mnf.Option{mnf.WithFlavour(flavour)}
+	if config.Hash != "" {
+		algorithm, ok := mnf.GetHashAlgorithm(config.Hash)
+		if !ok {
+			return fmt.Errorf("unknown hash algorithm: %s", config.Hash)
+		}
+		options = append(options, mnf.WithHashAlgorithm(algorithm))
+	}
+
+	log.Printf("Parsing %q...", inputFilePath)
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, options...)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseContext: %s", err)
+	}
+	defer mnfData.Close()
+
+	log.Printf("Searching...")
+
+	_, found, ok := mnfData.Lookup(searchRecord.Id, searchRecord.Field2, searchRecord.Flags)
+	if !ok {
+		return fmt.Errorf("record %s not found", config.Id)
+	}
+
+	log.Printf("Replacing...")
+	err = mnfData.Replace(found, data)
+	if err != nil {
+		return fmt.Errorf("mnfData.Replace: %s", err)
+	}
+
+	log.Printf("Replaced: archive %d, offset %d, size %d", found.ArchiveIndex, found.Offset, found.CompressedSize)
+
+	return nil
+}
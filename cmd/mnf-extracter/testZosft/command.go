@@ -3,9 +3,10 @@ package testZosft
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"github.com/eso-tools/eso-tools/extracter"
 	"github.com/eso-tools/eso-tools/mnf"
+	"github.com/eso-tools/eso-tools/reader"
 	"github.com/eso-tools/eso-tools/zosft"
 	"github.com/jessevdk/go-flags"
 	"log"
@@ -14,7 +15,8 @@ import (
 )
 
 type Config struct {
-	Input string `long:"input" short:"i" required:"true"`
+	Input   string `long:"input" short:"i" required:"true"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
 }
 
 func Command(ctx context.Context, args []string) error {
@@ -38,46 +40,43 @@ func Command(ctx context.Context, args []string) error {
 		return fmt.Errorf("'%s' is not a file", inputFilePath)
 	}
 
-	mnfData, err := mnf.Parse(inputFilePath)
+	flavour, err := mnf.ParseFlavour(config.Flavour)
 	if err != nil {
-		return fmt.Errorf("mnf.Parse: %s", err)
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
 	}
 
-	recordChan := make(chan *extracter.Record, 100)
-	errorChan := make(chan error, 1)
-
-	go func() {
-		extracter.CombineRecords(mnfData, recordChan, errorChan)
-	}()
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, mnf.WithFlavour(flavour))
+	if err != nil {
+		return fmt.Errorf("mnf.ParseContext: %s", err)
+	}
+	defer mnfData.Close()
 
 	log.Printf("Scanning...")
 
-Loop:
-	for {
-		select {
-		case record, ok := <-recordChan:
-			if !ok {
-				break Loop
-			}
-
-			data, err := mnfData.Read(record.Record3)
-			if err != nil {
-				return fmt.Errorf("mnfData.Read: %s", err)
-			}
+	for record, err := range mnfData.Records() {
+		if record == nil {
+			return fmt.Errorf("mnfData.Records: %s", err)
+		}
 
-			_, err = zosft.Parse(bytes.NewReader(data))
-			if err == nil {
-				log.Printf("zosft id: %d [0x%08x]", record.Record2.Id, record.Record2.Id)
-				break Loop
-			}
+		if err != nil {
+			continue
+		}
 
-		case err, ok := <-errorChan:
-			if ok {
-				return fmt.Errorf("extracter.CombineRecords: %s", err)
-			}
+		data, err := mnfData.ReadContext(ctx, record.Record3)
+		if err != nil {
+			return fmt.Errorf("mnfData.ReadContext: %s", err)
+		}
 
-		default:
+		_, err = zosft.Parse(bytes.NewReader(data))
+		if err == nil {
+			log.Printf("zosft id: %d [0x%08x]", record.Record2.Id, record.Record2.Id)
+			break
+		}
 
+		// a record with the zosft signature that does not parse shows where the format has changed
+		var formatError *reader.FormatError
+		if errors.As(err, &formatError) && formatError.Field != "Signature" {
+			log.Printf("zosft id: %d [0x%08x] does not parse: %s", record.Record2.Id, record.Record2.Id, err)
 		}
 	}
 
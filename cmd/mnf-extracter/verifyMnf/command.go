@@ -0,0 +1,255 @@
+package verifyMnf
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/eso-tools/eso-tools/format"
+	"github.com/eso-tools/eso-tools/mnf"
+	"github.com/jessevdk/go-flags"
+	workerpool "github.com/zelenin/go-worker-pool"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	defaultThreads uint8 = 3
+	maxThreads     uint8 = 16
+)
+
+type Config struct {
+	Input   string `long:"input" short:"i" required:"true"`
+	Output  string `long:"output" short:"o" required:"true"`
+	Threads uint8  `long:"threads" short:"t"`
+	Flavour string `long:"flavour" choice:"auto" choice:"game" choice:"depot" choice:"other"`
+	Hash    string `long:"hash"`
+}
+
+type failure struct {
+	index  int
+	status string
+	err    error
+}
+
+func Command(ctx context.Context, args []string) error {
+	var config Config
+	_, err := flags.ParseArgs(&config, args[1:])
+	if err != nil {
+		return nil
+	}
+
+	inputFilePath, err := filepath.Abs(filepath.Clean(config.Input))
+	if err != nil {
+		return fmt.Errorf("filepath.Abs: %s", err)
+	}
+
+	inputFileInfo, err := os.Stat(inputFilePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("'%s' does not exist", inputFilePath)
+	}
+
+	if inputFileInfo.IsDir() {
+		return fmt.Errorf("'%s' is not a file", inputFilePath)
+	}
+
+	threads := config.Threads
+	if threads < 1 || threads > maxThreads {
+		threads = defaultThreads
+	}
+
+	flavour, err := mnf.ParseFlavour(config.Flavour)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseFlavour: %s", err)
+	}
+
+	options := []mnf.Option{mnf.WithFlavour(flavour)}
+	if config.Hash != "" {
+		algorithm, ok := mnf.GetHashAlgorithm(config.Hash)
+		if !ok {
+			return fmt.Errorf("unknown hash algorithm: %s", config.Hash)
+		}
+		options = append(options, mnf.WithHashAlgorithm(algorithm))
+	}
+
+	log.Printf("Parsing %q...", inputFilePath)
+	mnfData, err := mnf.ParseContext(ctx, inputFilePath, options...)
+	if err != nil {
+		return fmt.Errorf("mnf.ParseContext: %s", err)
+	}
+	defer mnfData.Close()
+
+	for _, warning := range mnfData.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	log.Printf("Flavour: %s", mnfData.GetFlavour())
+
+	algorithm := mnfData.HashAlgorithm()
+	if algorithm == nil {
+		log.Printf("Hashes are not checked, the algorithm is not identified (see --hash)")
+	} else {
+		log.Printf("Hash algorithm: %s", algorithm)
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []*failure
+		verified atomic.Int64
+		total    int
+	)
+
+	addFailure := func(i int, err error) {
+		mu.Lock()
+		failures = append(failures, &failure{
+			index:  i,
+			status: getStatus(err),
+			err:    err,
+		})
+		mu.Unlock()
+	}
+
+	pool := workerpool.NewPool(int64(threads), 1000)
+
+	log.Printf("Verifying...")
+
+	// the leading records of a depot are skipped like by the other commands
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			return fmt.Errorf("mnfData.Records: %s", err)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		total++
+		i := entry.Index
+
+		if err != nil {
+			verified.Add(1)
+			addFailure(i, err)
+			continue
+		}
+
+		pool.AddTask(func(context.Context) error {
+			// the queued records are dropped on cancellation, the report lists the failures found so far
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if (i+1)%10000 == 0 {
+				log.Printf("Record %d/%d", i+1, mnfData.Index3.Len())
+			}
+
+			err := mnfData.Verify(entry.Record3)
+			verified.Add(1)
+			if err != nil {
+				addFailure(i, err)
+			}
+
+			return nil
+		})
+	}
+
+	pool.Wait()
+
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].index < failures[j].index
+	})
+
+	err = os.MkdirAll(filepath.Dir(config.Output), 0777)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %s", err)
+	}
+
+	f, err := os.Create(config.Output)
+	if err != nil {
+		return fmt.Errorf("os.Create: %s", err)
+	}
+	defer f.Close()
+
+	log.Printf("Writing \"%s\"...", config.Output)
+
+	csvWriter := csv.NewWriter(f)
+
+	csvWriter.Write([]string{
+		"Index",
+		"Id",
+		"Field2",
+		"Flags",
+		"UncompressedSize",
+		"CompressedSize",
+		"Hash",
+		"Offset",
+		"ArchiveIndex",
+		"CompressionType",
+		"Status",
+		"Error",
+	})
+
+	for _, failure := range failures {
+		block2Record := mnfData.Index3.Block2Record(failure.index)
+		block3Record := mnfData.Index3.Block3Record(failure.index)
+
+		csvWriter.Write([]string{
+			fmt.Sprintf("%d", failure.index),
+			fmt.Sprintf("0x%08x", block2Record.Id),
+			fmt.Sprintf("%s", format.BytesFormat(block2Record.Field2)),
+			fmt.Sprintf("%s", format.BytesFormat(block2Record.Flags)),
+			fmt.Sprintf("%d", block3Record.UncompressedSize),
+			fmt.Sprintf("%d", block3Record.CompressedSize),
+			fmt.Sprintf("0x%08x", block3Record.Hash),
+			fmt.Sprintf("%d", block3Record.Offset),
+			fmt.Sprintf("%d", block3Record.ArchiveIndex),
+			fmt.Sprintf("%d", block3Record.CompressionType),
+			failure.status,
+			failure.err.Error(),
+		})
+	}
+
+	csvWriter.Flush()
+
+	err = csvWriter.Error()
+	if err != nil {
+		return fmt.Errorf("csvWriter.Write: %s", err)
+	}
+
+	log.Printf("Verified %d/%d records, %d failed", verified.Load(), total, len(failures))
+
+	err = ctx.Err()
+	if err != nil {
+		return fmt.Errorf("verification stopped: %s", err)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d records failed verification", len(failures))
+	}
+
+	return nil
+}
+
+func getStatus(err error) string {
+	switch {
+	case errors.Is(err, mnf.ErrorMissingArchive):
+		return "missing_archive"
+
+	case errors.Is(err, mnf.ErrorNotValidRecord):
+		return "out_of_range"
+
+	case errors.Is(err, mnf.ErrorDecompress):
+		return "decompression_failed"
+
+	case errors.Is(err, mnf.ErrorSizeMismatch):
+		return "size_mismatch"
+
+	case errors.Is(err, mnf.ErrorHashMismatch):
+		return "hash_mismatch"
+	}
+
+	return "read_failed"
+}
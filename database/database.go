@@ -38,7 +38,13 @@ type RecordMeta struct {
 	DateTime uint32
 }
 
+// ParseDatabase parses a database file with limits.
 func ParseDatabase(r io.Reader) (*Database, error) {
+	return ParseDatabaseWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseDatabaseWithLimits parses a database file, the number of records and their sizes are bounded by limits.
+func ParseDatabaseWithLimits(r io.Reader, limits reader.Limits) (*Database, error) {
 	var data []byte
 	var err error
 
@@ -46,82 +52,96 @@ func ParseDatabase(r io.Reader) (*Database, error) {
 		Records: []*Record{},
 	}
 
-	data, err = reader.ReadBytes(r, len(databaseSignature))
+	d := reader.NewDecoder(r, "database")
+
+	data, err = d.ReadBytes("Signature", len(databaseSignature))
 	if err != nil {
 		return nil, err
 	}
 
 	if !bytes.Equal(data, databaseSignature) {
-		return nil, errors.New("wrong signature")
+		return nil, d.Error("Signature", errors.New("wrong signature"))
 	}
 	db.Signature = data
 
-	field2, err := reader.ReadUint32(r, binary.BigEndian)
+	field2, err := d.ReadUint32("Field2", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	db.Field2 = field2
 
-	field3, err := reader.ReadUint32(r, binary.BigEndian)
+	field3, err := d.ReadUint32("Count", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	db.Count = field3
 
-	field4, err := reader.ReadUint32(r, binary.BigEndian)
+	field4, err := d.ReadUint32("Version", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	db.Version = field4
 
+	err = limits.CheckCount(uint64(db.Count))
+	if err != nil {
+		return nil, d.Error("Count", err)
+	}
+
 	for i := 0; i < int(db.Count); i++ {
 		record := &Record{}
 
-		uncomressedSize1, err := reader.ReadUint32(r, binary.BigEndian)
+		uncomressedSize1, err := d.ReadUint32("Record.UncomressedSize1", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.UncomressedSize1 = uncomressedSize1
 
-		uncomressedSize2, err := reader.ReadUint32(r, binary.BigEndian)
+		uncomressedSize2, err := d.ReadUint32("Record.UncomressedSize2", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.UncomressedSize2 = uncomressedSize2
 
-		dataSize, err := reader.ReadUint32(r, binary.BigEndian)
+		dataSize, err := d.ReadUint32("Record.ComressedSize", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.ComressedSize = dataSize
 
-		lr := io.LimitReader(r, int64(record.ComressedSize))
+		err = limits.CheckSize(uint64(record.ComressedSize))
+		if err != nil {
+			return nil, d.Error("Record.ComressedSize", err)
+		}
+
+		// the errors in the compressed data are reported at the offset of the record data
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(record.ComressedSize))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.Data", offset, err)
 		}
 
 		id, err := reader.ReadUint32(zr, binary.BigEndian)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.Id", offset, err)
 		}
 		record.Id = id
 
 		nameSize, err := reader.ReadUint16(zr, binary.BigEndian)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.NameSize", offset, err)
 		}
 		record.NameSize = nameSize
 
 		data, err = reader.ReadBytes(zr, int(record.NameSize))
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.Name", offset, err)
 		}
 		record.Name = string(data)
 
-		data, err = io.ReadAll(zr)
+		data, err = limits.ReadAll(zr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.Data", offset, err)
 		}
 		record.Data = data
 
@@ -129,7 +149,7 @@ func ParseDatabase(r io.Reader) (*Database, error) {
 
 		err = zr.Close()
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Record.Data", offset, err)
 		}
 	}
 
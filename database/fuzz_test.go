@@ -0,0 +1,56 @@
+package database
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/eso-tools/eso-tools/reader"
+	"testing"
+)
+
+var fuzzLimits = reader.Limits{
+	MaxSize:  1 << 20,
+	MaxCount: 1 << 16,
+}
+
+func FuzzParseDatabase(f *testing.F) {
+	name := []byte("name")
+	payload := &bytes.Buffer{}
+	binary.Write(payload, binary.BigEndian, uint32(1))
+	binary.Write(payload, binary.BigEndian, uint16(len(name)))
+	payload.Write(name)
+	payload.WriteString("data")
+
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	zw.Write(payload.Bytes())
+	zw.Close()
+
+	buf := &bytes.Buffer{}
+	buf.Write(databaseSignature)
+	binary.Write(buf, binary.BigEndian, []uint32{0, 1, 0})
+	binary.Write(buf, binary.BigEndian, []uint32{uint32(payload.Len()), uint32(payload.Len()), uint32(compressed.Len())})
+	buf.Write(compressed.Bytes())
+
+	f.Add(buf.Bytes())
+	f.Add(databaseSignature)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseDatabaseWithLimits(bytes.NewReader(data), fuzzLimits)
+	})
+}
+
+func FuzzParseIndex(f *testing.F) {
+	buf := &bytes.Buffer{}
+	buf.Write(indexSignature)
+	binary.Write(buf, binary.BigEndian, uint32(0))
+	binary.Write(buf, binary.BigEndian, uint16(0))
+	binary.Write(buf, binary.BigEndian, []uint32{0, 0, 0, 0, 1, 1, 16})
+
+	f.Add(buf.Bytes())
+	f.Add(indexSignature)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseIndexWithLimits(bytes.NewReader(data), fuzzLimits)
+	})
+}
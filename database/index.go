@@ -22,7 +22,13 @@ type Index struct {
 	Offsets   map[uint32]uint32
 }
 
+// ParseIndex parses a database index with limits.
 func ParseIndex(r io.Reader) (*Index, error) {
+	return ParseIndexWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseIndexWithLimits parses a database index, the number of offsets is bounded by limits.
+func ParseIndexWithLimits(r io.Reader, limits reader.Limits) (*Index, error) {
 	var data []byte
 	var err error
 
@@ -30,65 +36,72 @@ func ParseIndex(r io.Reader) (*Index, error) {
 		Offsets: map[uint32]uint32{},
 	}
 
-	data, err = reader.ReadBytes(r, len(indexSignature))
+	d := reader.NewDecoder(r, "database index")
+
+	data, err = d.ReadBytes("Signature", len(indexSignature))
 	if err != nil {
 		return nil, err
 	}
 
 	if !bytes.Equal(data, indexSignature) {
-		return nil, errors.New("wrong signature")
+		return nil, d.Error("Signature", errors.New("wrong signature"))
 	}
 	index.Signature = data
 
-	field2, err := reader.ReadUint32(r, binary.BigEndian)
+	field2, err := d.ReadUint32("Field2", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field2 = field2
 
-	field3, err := reader.ReadUint16(r, binary.BigEndian)
+	field3, err := d.ReadUint16("Field3", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field3 = field3
 
-	field4, err := reader.ReadUint32(r, binary.BigEndian)
+	field4, err := d.ReadUint32("Field4", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field4 = field4
 
-	field5, err := reader.ReadUint32(r, binary.BigEndian)
+	field5, err := d.ReadUint32("Field5", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field5 = field5
 
-	field6, err := reader.ReadUint32(r, binary.BigEndian)
+	field6, err := d.ReadUint32("Field6", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field6 = field6
 
-	field7, err := reader.ReadUint32(r, binary.BigEndian)
+	field7, err := d.ReadUint32("Field7", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Field7 = field7
 
-	count, err := reader.ReadUint32(r, binary.BigEndian)
+	count, err := d.ReadUint32("Count", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	index.Count = count
 
+	err = limits.CheckCount(uint64(index.Count))
+	if err != nil {
+		return nil, d.Error("Count", err)
+	}
+
 	for i := 0; i < int(index.Count); i++ {
-		id, err := reader.ReadUint32(r, binary.BigEndian)
+		id, err := d.ReadUint32("Offsets.Id", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 
-		offset, err := reader.ReadUint32(r, binary.BigEndian)
+		offset, err := d.ReadUint32("Offsets.Offset", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
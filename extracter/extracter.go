@@ -1,90 +1,94 @@
 package extracter
 
 import (
+	"bufio"
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"github.com/eso-tools/eso-tools/mnf"
+	"io"
 )
 
+// Record is a stored file with its data.
 type Record struct {
-	Record2  *mnf.Block2Record
-	Record3  *mnf.Block3Record
-	FileName string
-	Data     []byte
+	*mnf.Entry
+	Data []byte
 }
 
 func (record *Record) GetExtension() string {
 	return GetExtension(record.Data)
 }
 
+type dataReader struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// Open opens the data of record and keeps its first bytes in Data, enough for GetExtension and GetRawFilename. The
+// reader starts at the beginning of the data.
+func (record *Record) Open(ctx context.Context, mnfData *mnf.Mnf) (io.ReadCloser, error) {
+	entryReader, err := mnfData.OpenContext(ctx, record.Record3)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(entryReader)
+
+	// the extension is detected by the first bytes
+	chunkStart, err := reader.Peek(8)
+	if err != nil && err != io.EOF {
+		entryReader.Close()
+		return nil, err
+	}
+
+	record.Data = bytes.Clone(chunkStart)
+
+	return &dataReader{
+		Reader: reader,
+		Closer: entryReader,
+	}, nil
+}
+
+// GetRawId returns the id, field2 and flags of the record as in its raw file name.
+func (record *Record) GetRawId() string {
+	return fmt.Sprintf("0x%08x-%08x", record.Record2.Id, append(record.Record2.Field2, record.Record2.Flags...))
+}
+
 func (record *Record) GetRawFilename() string {
-	return fmt.Sprintf("0x%08x-%08x.%s", record.Record2.Id, append(record.Record2.Field2, record.Record2.Flags...), record.GetExtension())
+	return fmt.Sprintf("%s.%s", record.GetRawId(), record.GetExtension())
 }
 
-var twoZeroBytes = []byte{0x00, 0x00}
+// Lookup returns the record with id, field2 and flags with its file name as given by mnf.Mnf.Records, see
+// mnf.Mnf.Lookup.
+func Lookup(mnfData *mnf.Mnf, id uint32, field2 []byte, flags []byte) (*Record, bool, error) {
+	record2, record3, ok := mnfData.Lookup(id, field2, flags)
+	if !ok {
+		return nil, false, nil
+	}
 
-func CombineRecords(mnfData *mnf.Mnf, recordChan chan *Record, errorChan chan error) {
-	defer func() {
-		close(recordChan)
-		close(errorChan)
-	}()
+	record := &Record{
+		Entry: &mnf.Entry{
+			Record2: record2,
+			Record3: record3,
+		},
+	}
 
-	zosftData, err := mnfData.GetZosft()
+	fileName, ok, err := mnfData.GetFileName(id)
 	if err != nil {
-		errorChan <- err
-		return
+		return nil, false, err
 	}
 
-	fileNames := map[uint32]string{}
-	if zosftData != nil {
-		fileNames = zosftData.GetFileNamesById()
+	if !ok {
+		return record, true, nil
 	}
 
-	rowCount2 := len(mnfData.Index3.Block2Records)
-	rowCount3 := len(mnfData.Index3.Block3Records)
-	if rowCount2 != rowCount3 {
-		errorChan <- errors.New("rowCount2 != rowCount3")
-		return
+	if mnfData.IsNamed(record2) {
+		record.FileName = fileName
+	} else {
+		record.FileName = mnf.GetVariantFileName(fileName, record2)
 	}
 
-	isDepot := mnfData.IsDepot()
-	skip := isDepot
-
-	for i := 0; i < rowCount2; i++ {
-		record := &Record{
-			Record2: mnfData.Index3.Block2Records[i],
-			Record3: mnfData.Index3.Block3Records[i],
-		}
-
-		if isDepot && skip && record.Record3.ArchiveIndex != 0 {
-			skip = false
-		}
-
-		if skip {
-			continue
-		}
-
-		archive, ok := mnfData.Archives[record.Record3.ArchiveIndex]
-		if !ok {
-			errorChan <- fmt.Errorf("not valid archiveIndex: %d", record.Record3.ArchiveIndex)
-			return
-		}
-
-		if !archive.IsValid(record.Record3) {
-			continue
-		}
-
-		_, ok = fileNames[record.Record2.Id]
-		if ok {
-			if bytes.Equal(twoZeroBytes, record.Record2.Field2) {
-				record.FileName = fileNames[record.Record2.Id]
-				delete(fileNames, record.Record2.Id)
-			}
-		}
-
-		recordChan <- record
-	}
+	return record, true, nil
 }
 
 func GetExtension(data []byte) string {
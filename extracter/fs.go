@@ -0,0 +1,424 @@
+package extracter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/eso-tools/eso-tools/mnf"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RawDir is the directory holding the records without a ZOSFT file name.
+const RawDir = "_raw"
+
+// FS is a read-only fs.FS view over the files of a .mnf archive. Named files are placed by their ZOSFT path, the
+// others are found in RawDir as GetRawFilename. The extension of raw files is detected the first time RawDir is looked
+// at, the start of every raw file is read then.
+type FS struct {
+	mnfData *mnf.Mnf
+	modTime time.Time
+	nodes   map[string]*fsNode
+
+	// raw holds the records of RawDir, they are placed in rawNodes by indexRaw
+	raw      []*Record
+	rawOnce  sync.Once
+	rawNodes map[string]*fsNode
+}
+
+type fsNode struct {
+	name     string
+	record   *Record
+	children []*fsNode
+
+	sizeOnce sync.Once
+	size     int64
+	sizeErr  error
+}
+
+func (node *fsNode) isDir() bool {
+	return node.record == nil
+}
+
+// NewFS indexes the records of mnfData. The archives are read only when files are opened or RawDir is looked at,
+// uncompressed files are read directly from the archive.
+func NewFS(mnfData *mnf.Mnf) (*FS, error) {
+	fsys := &FS{
+		mnfData: mnfData,
+		nodes: map[string]*fsNode{
+			".": {
+				name: ".",
+			},
+		},
+	}
+
+	fileInfo, err := os.Stat(mnfData.Path)
+	if err == nil {
+		fsys.modTime = fileInfo.ModTime()
+	}
+
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			return nil, err
+		}
+
+		// the records that cannot be read are left out
+		if err != nil {
+			continue
+		}
+
+		record := &Record{Entry: entry}
+		if record.FileName != "" {
+			name := normalizeFileName(record.FileName)
+			if !isRawName(name) && fsys.add(name, record) {
+				continue
+			}
+		}
+
+		fsys.raw = append(fsys.raw, record)
+	}
+
+	if len(fsys.raw) > 0 {
+		root := fsys.nodes["."]
+		fsys.nodes[RawDir] = &fsNode{
+			name: RawDir,
+		}
+		root.children = append(root.children, fsys.nodes[RawDir])
+	}
+
+	for _, node := range fsys.nodes {
+		slices.SortFunc(node.children, func(a *fsNode, b *fsNode) int {
+			return strings.Compare(a.name, b.name)
+		})
+	}
+
+	return fsys, nil
+}
+
+func normalizeFileName(fileName string) string {
+	fileName = strings.ReplaceAll(fileName, "\\", "/")
+	fileName = strings.TrimLeft(fileName, "/")
+	if fileName == "" {
+		return ""
+	}
+
+	return path.Clean(fileName)
+}
+
+// isRawName reports whether name is RawDir or is inside it, only raw files are placed there.
+func isRawName(name string) bool {
+	return name == RawDir || strings.HasPrefix(name, RawDir+"/")
+}
+
+// indexRaw places the records of RawDir by their raw file name, the records that cannot be read are left out.
+func (fsys *FS) indexRaw() {
+	dir, ok := fsys.nodes[RawDir]
+	if !ok {
+		return
+	}
+
+	fsys.rawNodes = map[string]*fsNode{}
+
+	for _, record := range fsys.raw {
+		r, err := record.Open(context.Background(), fsys.mnfData)
+		if err != nil {
+			continue
+		}
+		r.Close()
+
+		name := path.Join(RawDir, record.GetRawFilename())
+		_, ok := fsys.rawNodes[name]
+		if ok {
+			continue
+		}
+
+		node := &fsNode{
+			name:   path.Base(name),
+			record: record,
+		}
+		fsys.rawNodes[name] = node
+		dir.children = append(dir.children, node)
+	}
+
+	slices.SortFunc(dir.children, func(a *fsNode, b *fsNode) int {
+		return strings.Compare(a.name, b.name)
+	})
+}
+
+// add places record at name and reports false when name is not valid or is already taken.
+func (fsys *FS) add(name string, record *Record) bool {
+	if name == "." || !fs.ValidPath(name) {
+		return false
+	}
+
+	_, ok := fsys.nodes[name]
+	if ok {
+		return false
+	}
+
+	dir := path.Dir(name)
+	for dir != "." {
+		node, ok := fsys.nodes[dir]
+		if ok && !node.isDir() {
+			return false
+		}
+		dir = path.Dir(dir)
+	}
+
+	node := &fsNode{
+		name:   path.Base(name),
+		record: record,
+	}
+	fsys.nodes[name] = node
+
+	for {
+		dir = path.Dir(name)
+		parent, ok := fsys.nodes[dir]
+		if ok {
+			parent.children = append(parent.children, node)
+			return true
+		}
+
+		parent = &fsNode{
+			name:     path.Base(dir),
+			children: []*fsNode{node},
+		}
+		fsys.nodes[dir] = parent
+
+		name = dir
+		node = parent
+	}
+}
+
+func (fsys *FS) lookup(op string, name string) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	nodes := fsys.nodes
+	if isRawName(name) {
+		fsys.rawOnce.Do(fsys.indexRaw)
+		if name != RawDir {
+			nodes = fsys.rawNodes
+		}
+	}
+
+	node, ok := nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return node, nil
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	node, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.isDir() {
+		return &fsDir{
+			fsys: fsys,
+			node: node,
+		}, nil
+	}
+
+	section, err := fsys.mnfData.OpenSection(node.record.Record3)
+	if err == mnf.ErrorCompressedRecord {
+		var data []byte
+		data, err = fsys.mnfData.Read(node.record.Record3)
+		section = io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &fsFile{
+		SectionReader: section,
+		info: &fileInfo{
+			node:    node,
+			size:    section.Size(),
+			modTime: fsys.modTime,
+		},
+	}, nil
+}
+
+// Stat reports the size of the data without its header, the data of compressed files is decompressed up to the end of
+// the header.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	node, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fsys.info(node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !node.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	return fsys.dirEntries(node.children), nil
+}
+
+func (fsys *FS) info(node *fsNode) (*fileInfo, error) {
+	info := &fileInfo{
+		node:    node,
+		modTime: fsys.modTime,
+	}
+
+	if !node.isDir() {
+		node.sizeOnce.Do(func() {
+			node.size, node.sizeErr = fsys.mnfData.Size(node.record.Record3)
+		})
+		if node.sizeErr != nil {
+			return nil, node.sizeErr
+		}
+		info.size = node.size
+	}
+
+	return info, nil
+}
+
+// dirEntries returns the entries of nodes, the size of a file is found when Info is called.
+func (fsys *FS) dirEntries(nodes []*fsNode) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(nodes))
+	for i, node := range nodes {
+		entries[i] = &dirEntry{
+			fsys: fsys,
+			node: node,
+		}
+	}
+
+	return entries
+}
+
+type dirEntry struct {
+	fsys *FS
+	node *fsNode
+}
+
+func (entry *dirEntry) Name() string {
+	return entry.node.name
+}
+
+func (entry *dirEntry) IsDir() bool {
+	return entry.node.isDir()
+}
+
+func (entry *dirEntry) Type() fs.FileMode {
+	if entry.node.isDir() {
+		return fs.ModeDir
+	}
+
+	return 0
+}
+
+func (entry *dirEntry) Info() (fs.FileInfo, error) {
+	info, err := entry.fsys.info(entry.node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: entry.node.name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (entry *dirEntry) String() string {
+	return fs.FormatDirEntry(entry)
+}
+
+type fileInfo struct {
+	node    *fsNode
+	size    int64
+	modTime time.Time
+}
+
+func (info *fileInfo) Name() string {
+	return info.node.name
+}
+
+func (info *fileInfo) Size() int64 {
+	return info.size
+}
+
+func (info *fileInfo) Mode() fs.FileMode {
+	if info.node.isDir() {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+func (info *fileInfo) ModTime() time.Time {
+	return info.modTime
+}
+
+func (info *fileInfo) IsDir() bool {
+	return info.node.isDir()
+}
+
+func (info *fileInfo) Sys() any {
+	return info.node.record
+}
+
+type fsFile struct {
+	*io.SectionReader
+	info *fileInfo
+}
+
+func (file *fsFile) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+func (file *fsFile) Close() error {
+	return nil
+}
+
+type fsDir struct {
+	fsys   *FS
+	node   *fsNode
+	offset int
+}
+
+func (dir *fsDir) Stat() (fs.FileInfo, error) {
+	return dir.fsys.info(dir.node)
+}
+
+func (dir *fsDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: dir.node.name, Err: errors.New("is a directory")}
+}
+
+func (dir *fsDir) Close() error {
+	return nil
+}
+
+func (dir *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	children := dir.node.children[dir.offset:]
+	if n > 0 {
+		if len(children) == 0 {
+			return nil, io.EOF
+		}
+		children = children[:min(n, len(children))]
+	}
+	dir.offset += len(children)
+
+	return dir.fsys.dirEntries(children), nil
+}
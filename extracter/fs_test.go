@@ -0,0 +1,97 @@
+package extracter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/eso-tools/eso-tools/mnf"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	header := binary.BigEndian.AppendUint32(make([]byte, 4), 4)
+	header = append(header, "head"...)
+	header = binary.BigEndian.AppendUint32(header, 0)
+
+	payloads := [][]byte{
+		[]byte("plain payload"),
+		append(header, "DDS payload after a header"...),
+		bytes.Repeat([]byte("long payload "), 100),
+	}
+	// the extension is detected after the header
+	exts := []string{"dat", "dds", "dat"}
+
+	writer := mnf.NewWriter(filepath.Join(t.TempDir(), "test.mnf"))
+	for i, payload := range payloads {
+		for compressionType := range 2 {
+			err := writer.Add(&mnf.WriteEntry{
+				Record2: &mnf.Block2Record{
+					Id:     uint32(2*i + compressionType + 1),
+					Field2: []byte{0x00, 0x00},
+					Flags:  []byte{0x00, 0x00},
+				},
+				CompressionType: uint16(compressionType),
+				Data:            payload,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData, err := mnf.Parse(writer.Path, mnf.WithCacheDir(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mnfData.Close()
+
+	fsys, err := NewFS(mnfData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for entry, err := range mnfData.Records() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, path.Join(RawDir, fmt.Sprintf("%s.%s", (&Record{Entry: entry}).GetRawId(), exts[len(names)/2])))
+	}
+
+	err = fstest.TestFS(fsys, names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range names {
+		want := payloads[i/2]
+		if i/2 == 1 {
+			want = want[len(header):]
+		}
+
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(want)) {
+			t.Fatalf("%s: size %d, want %d", name, info.Size(), len(want))
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Fatalf("%s: got %q, want %q", name, data, want)
+		}
+	}
+}
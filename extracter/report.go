@@ -0,0 +1,106 @@
+package extracter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Failure is a file that could not be extracted, Stage is the step that failed: "archive" for a record that is missing
+// from or out of its archive, "open", "mkdir", "create", "copy", "convert" or "hash".
+type Failure struct {
+	Id       string
+	FileName string
+	Stage    string
+	Err      error
+}
+
+// Report collects the failures of the workers of an extraction, it is safe for concurrent use.
+type Report struct {
+	mu        sync.Mutex
+	maxErrors int
+	failures  []*Failure
+}
+
+// NewReport returns a report that stops the extraction after maxErrors failures, 0 does not limit the failures.
+func NewReport(maxErrors int) *Report {
+	return &Report{
+		maxErrors: maxErrors,
+	}
+}
+
+// Add records the failure of record at stage and reports whether the extraction should stop.
+func (report *Report) Add(record *Record, stage string, err error) bool {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	report.failures = append(report.failures, &Failure{
+		Id:       record.GetRawId(),
+		FileName: record.FileName,
+		Stage:    stage,
+		Err:      err,
+	})
+
+	return report.isExceeded()
+}
+
+func (report *Report) isExceeded() bool {
+	return report.maxErrors > 0 && len(report.failures) >= report.maxErrors
+}
+
+// Failures returns the failures sorted by id.
+func (report *Report) Failures() []*Failure {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	failures := slices.Clone(report.failures)
+	slices.SortStableFunc(failures, func(a *Failure, b *Failure) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return failures
+}
+
+// Err returns an error counting the failures, nil when there are none.
+func (report *Report) Err() error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if len(report.failures) == 0 {
+		return nil
+	}
+
+	if report.isExceeded() {
+		return fmt.Errorf("%d files failed, the extraction was stopped", len(report.failures))
+	}
+
+	return fmt.Errorf("%d files failed", len(report.failures))
+}
+
+// WriteCsv writes the failures as .csv.
+func (report *Report) WriteCsv(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+
+	csvWriter.Write([]string{
+		"Id",
+		"FileName",
+		"Stage",
+		"Error",
+	})
+
+	for _, failure := range report.Failures() {
+		csvWriter.Write([]string{
+			failure.Id,
+			failure.FileName,
+			failure.Stage,
+			failure.Err.Error(),
+		})
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
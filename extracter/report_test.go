@@ -0,0 +1,120 @@
+package extracter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/eso-tools/eso-tools/mnf"
+	"sync"
+	"testing"
+)
+
+func newReportTestRecord(id uint32) *Record {
+	return &Record{
+		Entry: &mnf.Entry{
+			Record2: &mnf.Block2Record{
+				Id:     id,
+				Field2: []byte{0x00, 0x01},
+				Flags:  []byte{0x00, 0x02},
+			},
+			FileName: fmt.Sprintf("art/%d.dds", id),
+		},
+	}
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxErrors int
+		failures  int
+		// wantStop is the number of failures after which Add reports a stop, 0 when it never does.
+		wantStop int
+		wantErr  string
+	}{
+		{name: "no failure", maxErrors: 2},
+		{name: "unlimited", maxErrors: 0, failures: 5, wantErr: "5 files failed"},
+		{name: "below the limit", maxErrors: 3, failures: 2, wantErr: "2 files failed"},
+		{name: "limit reached", maxErrors: 2, failures: 2, wantStop: 2, wantErr: "2 files failed, the extraction was stopped"},
+		{name: "limit of one", maxErrors: 1, failures: 3, wantStop: 1, wantErr: "3 files failed, the extraction was stopped"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := NewReport(test.maxErrors)
+
+			stop := 0
+			for i := test.failures; i > 0; i-- {
+				if report.Add(newReportTestRecord(uint32(i)), "copy", errors.New("failed")) && stop == 0 {
+					stop = test.failures - i + 1
+				}
+			}
+
+			if stop != test.wantStop {
+				t.Fatalf("stop after %d failures, want %d", stop, test.wantStop)
+			}
+
+			err := report.Err()
+			if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || err.Error() != test.wantErr) {
+				t.Fatalf("got %v, want %q", err, test.wantErr)
+			}
+
+			failures := report.Failures()
+			if len(failures) != test.failures {
+				t.Fatalf("got %d failures, want %d", len(failures), test.failures)
+			}
+			for i, failure := range failures {
+				want := newReportTestRecord(uint32(i + 1))
+				if failure.Id != want.GetRawId() || failure.FileName != want.FileName {
+					t.Fatalf("failure %d: got %s %s, want %s %s", i, failure.Id, failure.FileName, want.GetRawId(), want.FileName)
+				}
+			}
+		})
+	}
+}
+
+func TestReportConcurrent(t *testing.T) {
+	report := NewReport(50)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	stops := 0
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if report.Add(newReportTestRecord(uint32(i)), "open", errors.New("failed")) {
+				mu.Lock()
+				stops++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(report.Failures()) != 100 {
+		t.Fatalf("got %d failures, want 100", len(report.Failures()))
+	}
+	if stops != 51 {
+		t.Fatalf("got %d stops, want 51", stops)
+	}
+}
+
+func TestReportWriteCsv(t *testing.T) {
+	report := NewReport(0)
+	report.Add(newReportTestRecord(2), "convert", errors.New("bad, \"dds\""))
+	report.Add(newReportTestRecord(1), "archive", mnf.ErrorMissingArchive)
+
+	var b bytes.Buffer
+	err := report.WriteCsv(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Id,FileName,Stage,Error\n" +
+		"0x00000001-00010002,art/1.dds,archive," + mnf.ErrorMissingArchive.Error() + "\n" +
+		"0x00000002-00010002,art/2.dds,convert,\"bad, \"\"dds\"\"\"\n"
+	if b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,93 @@
+package extracter
+
+import (
+	"context"
+	"fmt"
+	"github.com/eso-tools/eso-tools/mnf"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// GetOutputNames returns the paths record is extracted to, relative to the output directory: its raw file in the
+// directory of its archive and its ZOSFT file name when it has one. Data must be set, see Open.
+func (record *Record) GetOutputNames() []string {
+	names := []string{filepath.Join(fmt.Sprintf("%03d", record.Record3.ArchiveIndex), record.GetRawFilename())}
+	if record.FileName != "" {
+		names = append(names, record.FileName)
+	}
+
+	return names
+}
+
+// Extract writes the data of record to the files of GetOutputNames in outputDirPath in one pass and returns their names
+// and the stage that failed, see Failure. The data is written to hasher too when it is not nil. ctx stops the copy, a
+// file that is not written completely is removed.
+func Extract(ctx context.Context, mnfData *mnf.Mnf, record *Record, outputDirPath string, hasher io.Writer) ([]string, string, error) {
+	r, err := record.Open(ctx, mnfData)
+	if err != nil {
+		return nil, "open", err
+	}
+	defer r.Close()
+
+	names := record.GetOutputNames()
+	fpaths := make([]string, len(names))
+	for i, name := range names {
+		fpaths[i] = filepath.Join(outputDirPath, name)
+	}
+
+	var reader io.Reader = r
+	if hasher != nil {
+		reader = io.TeeReader(r, hasher)
+	}
+
+	stage, err := WriteFiles(reader, fpaths...)
+	if err != nil {
+		return nil, stage, err
+	}
+
+	return names, "", nil
+}
+
+// WriteFiles copies r to every file of fpaths in one pass and returns the stage that failed, see Failure. A copy that
+// is stopped or fails does not leave truncated files, all the files are removed.
+func WriteFiles(r io.Reader, fpaths ...string) (string, error) {
+	var dests []*os.File
+	removeAll := func() {
+		for _, dest := range dests {
+			dest.Close()
+			os.Remove(dest.Name())
+		}
+	}
+
+	writers := make([]io.Writer, 0, len(fpaths))
+	for _, fpath := range fpaths {
+		err := os.MkdirAll(filepath.Dir(fpath), 0755)
+		if err != nil {
+			removeAll()
+			return "mkdir", err
+		}
+
+		dest, err := os.Create(fpath)
+		if err != nil {
+			removeAll()
+			return "create", err
+		}
+		dests = append(dests, dest)
+		writers = append(writers, dest)
+	}
+
+	_, err := io.Copy(io.MultiWriter(writers...), r)
+	for _, dest := range dests {
+		closeErr := dest.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		removeAll()
+		return "copy", err
+	}
+
+	return "", nil
+}
@@ -0,0 +1,156 @@
+package extracter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"github.com/eso-tools/eso-tools/mnf"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	fpaths := []string{
+		filepath.Join(dir, "000", "0x00000001-00000000.dds"),
+		filepath.Join(dir, "art", "icon.dds"),
+	}
+	data := bytes.Repeat([]byte("payload "), 1000)
+
+	stage, err := WriteFiles(bytes.NewReader(data), fpaths...)
+	if err != nil {
+		t.Fatalf("%s: %s", stage, err)
+	}
+
+	for _, fpath := range fpaths {
+		written, err := os.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, data) {
+			t.Fatalf("%s: %d bytes, want %d", fpath, len(written), len(data))
+		}
+	}
+}
+
+func TestWriteFilesFailure(t *testing.T) {
+	dir := t.TempDir()
+	fpaths := []string{
+		filepath.Join(dir, "a.dat"),
+		filepath.Join(dir, "b.dat"),
+	}
+	copyErr := errors.New("read failed")
+
+	stage, err := WriteFiles(io.MultiReader(bytes.NewReader([]byte("partial")), &errorReader{err: copyErr}), fpaths...)
+	if stage != "copy" || !errors.Is(err, copyErr) {
+		t.Fatalf("got %s: %v, want copy: %v", stage, err, copyErr)
+	}
+
+	for _, fpath := range fpaths {
+		_, err = os.Stat(fpath)
+		if !os.IsNotExist(err) {
+			t.Fatalf("%s is not removed: %v", fpath, err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	data := append([]byte("DDS "), bytes.Repeat([]byte("payload "), 1000)...)
+
+	writer := mnf.NewWriter(filepath.Join(t.TempDir(), "test.mnf"))
+	err := writer.Add(&mnf.WriteEntry{
+		Record2: &mnf.Block2Record{
+			Id:     1,
+			Field2: []byte{0x00, 0x00},
+			Flags:  []byte{0x00, 0x00},
+		},
+		ArchiveIndex:    2,
+		CompressionType: 1,
+		Data:            data,
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData, err := mnf.Parse(writer.Path, mnf.WithCacheDir(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mnfData.Close()
+
+	record := &Record{
+		Entry: &mnf.Entry{
+			Record2:  mnfData.Index3.Block2Record(0),
+			Record3:  mnfData.Index3.Block3Record(0),
+			FileName: filepath.Join("art", "icon.dds"),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		cancelled bool
+		wantNames []string
+		wantStage string
+	}{
+		{
+			name:      "extracted",
+			wantNames: []string{filepath.Join("002", "0x00000001-00000000.dds"), filepath.Join("art", "icon.dds")},
+		},
+		{
+			name:      "cancelled",
+			cancelled: true,
+			wantStage: "open",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			dir := t.TempDir()
+			hasher := sha1.New()
+
+			names, stage, err := Extract(ctx, mnfData, record, dir, hasher)
+			if stage != test.wantStage || (err == nil) != (test.wantStage == "") {
+				t.Fatalf("got %s: %v, want stage %q", stage, err, test.wantStage)
+			}
+			if !slices.Equal(names, test.wantNames) {
+				t.Fatalf("got %q, want %q", names, test.wantNames)
+			}
+
+			for _, name := range names {
+				written, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(written, data) {
+					t.Fatalf("%s: %d bytes, want %d", name, len(written), len(data))
+				}
+			}
+
+			sum := sha1.Sum(data)
+			if names != nil && !bytes.Equal(hasher.Sum(nil), sum[:]) {
+				t.Fatalf("got the hash %x, want %x", hasher.Sum(nil), sum)
+			}
+		})
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
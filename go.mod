@@ -7,7 +7,6 @@ require (
 	github.com/new-world-tools/go-oodle v0.2.2
 	github.com/new-world-tools/new-world-tools v0.13.3
 	github.com/zelenin/go-app v0.0.0-20220319181535-7120aa0d458d
-	github.com/zelenin/go-binary v0.0.1
 	github.com/zelenin/go-texconv v0.0.2
 	github.com/zelenin/go-worker-pool v0.1.1
 )
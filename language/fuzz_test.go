@@ -0,0 +1,44 @@
+package language
+
+import (
+	"bytes"
+	"github.com/eso-tools/eso-tools/reader"
+	"testing"
+)
+
+var fuzzLimits = reader.Limits{
+	MaxSize:  1 << 20,
+	MaxCount: 1 << 16,
+}
+
+func FuzzParseReadStore(f *testing.F) {
+	buf := &bytes.Buffer{}
+	err := WriteWriteStore(buf, &WriteStore{
+		Records: []*WriteRecord{
+			{DomainId: 1, Variant: 0, Id: 1, Value: "first"},
+			{DomainId: 1, Variant: 1, Id: 1, Value: "second"},
+			{DomainId: 2, Variant: 0, Id: 1, Value: "first"},
+		},
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(buf.Bytes())
+	f.Add([]byte{0x00, 0x00, 0x00, 0x02})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		store, err := ParseReadStoreWithLimits(bytes.NewReader(data), fuzzLimits)
+		if err != nil {
+			return
+		}
+
+		for _, domainId := range store.GetDomainIds() {
+			for _, id := range store.GetIds(domainId) {
+				for _, record := range store.GetRecords(domainId, id) {
+					store.GetValueByRecord(record)
+				}
+			}
+		}
+	})
+}
@@ -71,7 +71,13 @@ type ReadRecord struct {
 	Offset   uint32
 }
 
+// ParseReadStore parses a .lang file for reading with reader.DefaultLimits.
 func ParseReadStore(r io.Reader) (*ReadStore, error) {
+	return ParseReadStoreWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseReadStoreWithLimits parses a .lang file for reading, the number of records is bounded by limits.
+func ParseReadStoreWithLimits(r io.Reader, limits reader.Limits) (*ReadStore, error) {
 	var (
 		u32   uint32
 		value string
@@ -84,48 +90,53 @@ func ParseReadStore(r io.Reader) (*ReadStore, error) {
 		recordMap:      map[uint32]map[uint32]map[uint32]*ReadRecord{},
 	}
 
-	buf := bufio.NewReaderSize(r, 1024*1024)
+	d := reader.NewDecoder(bufio.NewReaderSize(r, 1024*1024), "lang")
 
-	u32, err = reader.ReadUint32(buf, binary.BigEndian)
+	u32, err = d.ReadUint32("Signature", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 
 	if u32 != signature {
-		return nil, fmt.Errorf("wrong signature: %d", u32)
+		return nil, d.Error("Signature", fmt.Errorf("wrong signature: %d", u32))
 	}
 	store.Signature = u32
 
-	u32, err = reader.ReadUint32(buf, binary.BigEndian)
+	u32, err = d.ReadUint32("Count", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	store.Count = u32
 
+	err = limits.CheckCount(uint64(store.Count))
+	if err != nil {
+		return nil, d.Error("Count", err)
+	}
+
 	store.Records = make([]*ReadRecord, 0, store.Count)
 
 	for i := 0; i < int(store.Count); i++ {
 		record := &ReadRecord{}
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.DomainId", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.DomainId = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Variant", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.Variant = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Id", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.Id = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Offset", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
@@ -149,7 +160,7 @@ func ParseReadStore(r io.Reader) (*ReadStore, error) {
 	var currentOffset uint32
 
 	for {
-		value, err = reader.ReadNullTerminatedString(buf)
+		value, err = d.ReadNullTerminatedString("Values")
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -63,7 +63,13 @@ type WriteRecord struct {
 	Value    string
 }
 
+// ParseWriteStore parses a .lang file for editing with reader.DefaultLimits.
 func ParseWriteStore(r io.Reader) (*WriteStore, error) {
+	return ParseWriteStoreWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseWriteStoreWithLimits parses a .lang file for editing, the number of records is bounded by limits.
+func ParseWriteStoreWithLimits(r io.Reader, limits reader.Limits) (*WriteStore, error) {
 	var (
 		u32   uint32
 		value string
@@ -75,49 +81,54 @@ func ParseWriteStore(r io.Reader) (*WriteStore, error) {
 		recordMap: map[uint32]map[uint32]map[uint32]*WriteRecord{},
 	}
 
-	buf := bufio.NewReaderSize(r, 1024*1024)
+	d := reader.NewDecoder(bufio.NewReaderSize(r, 1024*1024), "lang")
 
-	u32, err = reader.ReadUint32(buf, binary.BigEndian)
+	u32, err = d.ReadUint32("Signature", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 
 	if u32 != signature {
-		return nil, fmt.Errorf("wrong signature: %d", u32)
+		return nil, d.Error("Signature", fmt.Errorf("wrong signature: %d", u32))
 	}
 	store.Signature = u32
 
-	u32, err = reader.ReadUint32(buf, binary.BigEndian)
+	u32, err = d.ReadUint32("Count", binary.BigEndian)
 	if err != nil {
 		return nil, err
 	}
 	store.Count = u32
 
+	err = limits.CheckCount(uint64(store.Count))
+	if err != nil {
+		return nil, d.Error("Count", err)
+	}
+
 	store.Records = make([]*WriteRecord, 0, store.Count)
 	recordsByOffset := map[uint32][]*WriteRecord{}
 
 	for i := 0; i < int(store.Count); i++ {
 		record := &WriteRecord{}
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.DomainId", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.DomainId = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Variant", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.Variant = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Id", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
 		record.Id = u32
 
-		u32, err = reader.ReadUint32(buf, binary.BigEndian)
+		u32, err = d.ReadUint32("Record.Offset", binary.BigEndian)
 		if err != nil {
 			return nil, err
 		}
@@ -148,7 +159,7 @@ func ParseWriteStore(r io.Reader) (*WriteStore, error) {
 	var currentOffset uint32
 
 	for {
-		value, err = reader.ReadNullTerminatedString(buf)
+		value, err = d.ReadNullTerminatedString("Values")
 		if err != nil {
 			if err == io.EOF {
 				break
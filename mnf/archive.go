@@ -1,101 +1,232 @@
 package mnf
 
 import (
+	"bufio"
 	"bytes"
-	"compress/zlib"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/eso-tools/eso-tools/reader"
-	"github.com/new-world-tools/go-oodle"
 	"io"
+	"math"
 	"os"
 	"sync"
 )
 
-func NewArchive(path string) (*Archive, error) {
+var (
+	ErrorCompressedRecord = errors.New("compressed record")
+	// ErrorNotAddressable is returned when a record does not fit the 32-bit offsets and sizes of the .mnf format.
+	ErrorNotAddressable = errors.New("record is not addressable by the .mnf format")
+)
+
+func NewArchive(path string, options ...Option) (*Archive, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Archive{
-		file: file,
-	}, nil
+	archive, err := newArchive(file, getOptions(options))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func newArchive(file ArchiveFile, opts *options) (*Archive, error) {
+	archive := &Archive{
+		file:        file,
+		keepHeaders: opts.keepHeaders,
+		limits:      opts.limits,
+	}
+
+	osFile, ok := file.(*os.File)
+	if opts.mmap && ok {
+		data, err := mmap(osFile)
+		if err != nil {
+			return nil, err
+		}
+		archive.data = data
+	}
+
+	err := archive.updateSize()
+	if err != nil {
+		if archive.data != nil {
+			munmap(archive.data)
+		}
+		return nil, err
+	}
+
+	return archive, nil
 }
 
+// Archive reads a .dat file with ReadAt, so it is safe for concurrent use.
 type Archive struct {
-	file *os.File
-	mu   sync.Mutex
+	file ArchiveFile
+
+	// mu guards data and size, they change when Replace grows the file
+	mu sync.RWMutex
+	// data is the mapped file when the archive is opened WithMmap from disk
+	data []byte
+	// size is the size of the file when it was opened or last refreshed
+	size int64
+
+	keepHeaders bool
+	limits      reader.Limits
 }
 
 func (archive *Archive) Close() error {
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+
+	if archive.data != nil {
+		err := munmap(archive.data)
+		if err != nil {
+			return err
+		}
+		archive.data = nil
+	}
+
 	return archive.file.Close()
 }
 
-func (archive *Archive) GetSize() int64 {
+// ReadAt reads from the mapped file when the archive is opened WithMmap and from the file otherwise.
+func (archive *Archive) ReadAt(p []byte, off int64) (int, error) {
+	archive.mu.RLock()
+	defer archive.mu.RUnlock()
+
+	if archive.data == nil {
+		return archive.file.ReadAt(p, off)
+	}
+
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if off >= int64(len(archive.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, archive.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// refresh maps the file again and updates its size after it has grown.
+func (archive *Archive) refresh() error {
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+
+	osFile, ok := archive.file.(*os.File)
+	if archive.data != nil && ok {
+		data, err := mmap(osFile)
+		if err != nil {
+			return err
+		}
+
+		err = munmap(archive.data)
+		archive.data = data
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.updateSize()
+}
+
+// updateSize is called with mu held or before the archive is shared.
+func (archive *Archive) updateSize() error {
+	if archive.data != nil {
+		archive.size = int64(len(archive.data))
+		return nil
+	}
+
 	fi, err := archive.file.Stat()
 	if err != nil {
-		return 0
+		return err
 	}
+	archive.size = fi.Size()
 
-	return fi.Size()
+	return nil
 }
 
-func (archive *Archive) IsValid(record *Block3Record) bool {
-	if (record.Offset + record.CompressedSize) > uint32(archive.GetSize()) {
-		return false
-	}
+func (archive *Archive) GetSize() int64 {
+	archive.mu.RLock()
+	defer archive.mu.RUnlock()
 
-	return true
+	return archive.size
+}
+
+func (archive *Archive) IsValid(record *Block3Record) bool {
+	return record.End() <= archive.GetSize()
 }
 
 func (archive *Archive) Read(record *Block3Record) ([]byte, error) {
-	data, err := archive.read(record)
+	data, _, err := archive.ReadWithDecompressor(record)
 	if err != nil {
 		return nil, err
 	}
 
-	switch record.CompressionType {
-	case 0:
+	return data, nil
+}
 
-	case 1: // ?
-		r := bytes.NewReader(data)
-		zlibReader, err := zlib.NewReader(r)
-		if err != nil {
-			return nil, err
-		}
-		defer zlibReader.Close()
+// ReadWithDecompressor is Read that also returns the decompressor registered for the record's compression type.
+func (archive *Archive) ReadWithDecompressor(record *Block3Record) ([]byte, *Decompressor, error) {
+	data, decompressor, err := archive.readDecompressed(record)
+	if err != nil {
+		return nil, decompressor, err
+	}
 
-		data, err = io.ReadAll(zlibReader)
-		if err != nil {
-			return nil, err
+	if !archive.keepHeaders {
+		header, ok := ParseEntryHeader(data)
+		if ok {
+			data = data[header.Size():]
 		}
+	}
 
-	case 4, 8:
-		data, err = oodle.Decompress(data, int64(record.UncompressedSize))
-		if err != nil {
-			return nil, err
-		}
+	return data, decompressor, nil
+}
 
-	default:
-		return nil, errors.New(fmt.Sprintf("unsupported compressionType: %d", record.CompressionType))
+// ReadWithHeader returns the payload of record without its header and the header, which is nil when there is none.
+func (archive *Archive) ReadWithHeader(record *Block3Record) ([]byte, *EntryHeader, error) {
+	data, _, err := archive.readDecompressed(record)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if data[0] == 0x00 && len(data) >= 16 {
-		cursor := uint32(0)
-		u := binary.BigEndian.Uint32(data[cursor : cursor+4])
-		if u == 0 {
-			cursor += 4
-			cursor += binary.BigEndian.Uint32(data[cursor : cursor+4])
-			cursor += 4
-			cursor += binary.BigEndian.Uint32(data[cursor : cursor+4])
-			cursor += 4
-		}
-		data = data[cursor:]
+	header, ok := ParseEntryHeader(data)
+	if !ok {
+		return data, nil, nil
 	}
 
-	return data, nil
+	return data[header.Size():], header, nil
+}
+
+func (archive *Archive) readDecompressed(record *Block3Record) ([]byte, *Decompressor, error) {
+	decompressor, ok := GetDecompressor(record.CompressionType)
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("unsupported compressionType: %d", record.CompressionType))
+	}
+
+	err := archive.limits.CheckSize(uint64(record.UncompressedSize))
+	if err != nil {
+		return nil, decompressor, err
+	}
+
+	data, err := archive.read(record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err = decompressor.Decompress(data, record.UncompressedSize)
+	if err != nil {
+		return nil, decompressor, fmt.Errorf("%s: %s", decompressor.Name, err)
+	}
+
+	return data, decompressor, nil
 }
 
 func (archive *Archive) ReadRaw(record *Block3Record) ([]byte, error) {
@@ -108,18 +239,133 @@ func (archive *Archive) ReadRaw(record *Block3Record) ([]byte, error) {
 }
 
 func (archive *Archive) read(record *Block3Record) ([]byte, error) {
-	archive.mu.Lock()
-	defer archive.mu.Unlock()
+	if !archive.IsValid(record) {
+		return nil, fmt.Errorf("%w: %d bytes at offset %d are out of the archive of %d bytes", ErrorNotValidRecord, record.CompressedSize, record.Offset, archive.GetSize())
+	}
 
-	_, err := archive.file.Seek(int64(record.Offset), io.SeekStart)
+	data := make([]byte, record.CompressedSize)
+	_, err := archive.ReadAt(data, int64(record.Offset))
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := reader.ReadBytes(archive.file, int(record.CompressedSize))
+	return data, nil
+}
+
+// Open returns a reader of the same data as Read. Records with a streaming decompressor are read from the archive as
+// they are consumed, the others are decompressed in memory.
+func (archive *Archive) Open(record *Block3Record) (io.ReadCloser, error) {
+	decompressor, ok := GetDecompressor(record.CompressionType)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unsupported compressionType: %d", record.CompressionType))
+	}
+
+	if decompressor.NewReader == nil {
+		data, err := archive.Read(record)
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	section := io.NewSectionReader(archive, int64(record.Offset), int64(record.CompressedSize))
+	r, err := decompressor.NewReader(bufio.NewReader(section))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", decompressor.Name, err)
+	}
+
+	entry := &entryReader{
+		Reader: bufio.NewReader(r),
+		closer: r,
+	}
+
+	if !archive.keepHeaders {
+		size := int64(record.UncompressedSize)
+		if record.CompressionType == 0 {
+			size = int64(record.CompressedSize)
+		}
+
+		entry.Reader, err = skipHeader(entry.Reader, size)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// OpenSection returns the data of an uncompressed record for random access.
+func (archive *Archive) OpenSection(record *Block3Record) (*io.SectionReader, error) {
+	if record.CompressionType != 0 {
+		return nil, ErrorCompressedRecord
+	}
+
+	section := io.NewSectionReader(archive, int64(record.Offset), int64(record.CompressedSize))
+	if archive.keepHeaders {
+		return section, nil
+	}
+
+	headerSize, err := getHeaderSize(section, section.Size())
 	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	return io.NewSectionReader(section, headerSize, section.Size()-headerSize), nil
+}
+
+// Size returns the size of the data returned by Read. Records with a streaming decompressor are decompressed up to the
+// end of their header, the others are read in full.
+func (archive *Archive) Size(record *Block3Record) (int64, error) {
+	decompressor, ok := GetDecompressor(record.CompressionType)
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unsupported compressionType: %d", record.CompressionType))
+	}
+
+	size := int64(record.UncompressedSize)
+	if record.CompressionType == 0 {
+		size = int64(record.CompressedSize)
+	}
+
+	if archive.keepHeaders {
+		return size, nil
+	}
+
+	if decompressor.NewReader == nil {
+		data, err := archive.Read(record)
+		if err != nil {
+			return 0, err
+		}
+
+		return int64(len(data)), nil
+	}
+
+	section := io.NewSectionReader(archive, int64(record.Offset), int64(record.CompressedSize))
+	r, err := decompressor.NewReader(bufio.NewReader(section))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", decompressor.Name, err)
+	}
+	defer r.Close()
+
+	headerSize, err := getHeaderSize(&prefixReader{r: r}, size)
+	if err != nil {
+		return 0, err
+	}
+
+	return size - headerSize, nil
+}
+
+// checkAddressable checks that a record of compressedSize and uncompressedSize written at offset can be stored in a
+// Block3Record.
+func checkAddressable(offset int64, compressedSize int, uncompressedSize int) error {
+	if offset < 0 || offset > math.MaxUint32 {
+		return fmt.Errorf("%w: offset %d", ErrorNotAddressable, offset)
+	}
+
+	if uint64(compressedSize) > math.MaxUint32 || uint64(uncompressedSize) > math.MaxUint32 {
+		return fmt.Errorf("%w: size %d (%d uncompressed)", ErrorNotAddressable, compressedSize, uncompressedSize)
+	}
+
+	return nil
 }
@@ -0,0 +1,268 @@
+package mnf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	benchmarkRecordCount = 256
+	benchmarkRecordSize  = 64 * 1024
+)
+
+func writeBenchmarkMnf(b *testing.B) string {
+	path := filepath.Join(b.TempDir(), "benchmark.mnf")
+
+	writer := NewWriter(path)
+	data := make([]byte, benchmarkRecordSize)
+	for i := 0; i < benchmarkRecordCount; i++ {
+		rand.Read(data)
+
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     uint32(i),
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			Data: data,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return path
+}
+
+// BenchmarkArchiveRead reads uncompressed records of one archive from a growing number of goroutines.
+func BenchmarkArchiveRead(b *testing.B) {
+	path := writeBenchmarkMnf(b)
+
+	for _, mmap := range []bool{false, true} {
+		for _, threads := range []int{1, 2, 4, 8, 16} {
+			b.Run(fmt.Sprintf("mmap=%t/threads=%d", mmap, threads), func(b *testing.B) {
+				options := []Option{WithCacheDir("")}
+				if mmap {
+					options = append(options, WithMmap())
+				}
+
+				mnfData, err := Parse(path, options...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer mnfData.Close()
+
+				records := make([]*Block3Record, mnfData.Index3.Len())
+				for i := range records {
+					records[i] = mnfData.Index3.Block3Record(i)
+				}
+
+				b.SetBytes(benchmarkRecordSize)
+				b.ResetTimer()
+
+				var next atomic.Int64
+				var wg sync.WaitGroup
+				for i := 0; i < threads; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+
+						for {
+							n := next.Add(1) - 1
+							if n >= int64(b.N) {
+								return
+							}
+
+							_, err := mnfData.Read(records[n%benchmarkRecordCount])
+							if err != nil {
+								b.Error(err)
+								return
+							}
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
+
+// TestArchiveRefresh reads a mapped archive while it grows and is mapped again, run it with -race.
+func TestArchiveRefresh(t *testing.T) {
+	path := writeTestMnf(t, t.TempDir(), false)
+	archivePath := getArchivePath(path, 0)
+
+	archive, err := NewArchive(archivePath, WithMmap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	f, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if archive.data == nil {
+		t.Skip("mmap is not supported")
+	}
+
+	var wg sync.WaitGroup
+	var done atomic.Bool
+	var reads atomic.Int64
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p := make([]byte, len(testPayloads[0]))
+			for !done.Load() {
+				_, err := archive.ReadAt(p, 0)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(p, testPayloads[0]) {
+					t.Errorf("got %q, want %q", p, testPayloads[0])
+					return
+				}
+				reads.Add(1)
+			}
+		}()
+	}
+
+	size := archive.GetSize()
+	for i := 0; i < 100; i++ {
+		for reads.Load() < int64(i) {
+			runtime.Gosched()
+		}
+
+		_, err = f.Write([]byte("appended"))
+		if err != nil {
+			break
+		}
+
+		err = archive.refresh()
+		if err != nil {
+			break
+		}
+	}
+	done.Store(true)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if archive.GetSize() != size+100*int64(len("appended")) {
+		t.Fatalf("size %d, want %d", archive.GetSize(), size+100*int64(len("appended")))
+	}
+}
+
+func TestCheckAddressable(t *testing.T) {
+	tests := []struct {
+		name             string
+		offset           int64
+		compressedSize   int64
+		uncompressedSize int64
+		wantErr          bool
+	}{
+		{name: "start"},
+		{name: "last offset", offset: math.MaxUint32, compressedSize: 10, uncompressedSize: 10},
+		{name: "largest sizes", compressedSize: math.MaxUint32, uncompressedSize: math.MaxUint32},
+		{name: "offset past 4 GiB", offset: math.MaxUint32 + 1, wantErr: true},
+		{name: "negative offset", offset: -1, wantErr: true},
+		{name: "compressed size past 4 GiB", compressedSize: math.MaxUint32 + 1, wantErr: true},
+		{name: "uncompressed size past 4 GiB", uncompressedSize: math.MaxUint32 + 1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if strconv.IntSize == 32 && (test.compressedSize > math.MaxInt32 || test.uncompressedSize > math.MaxInt32) {
+				t.Skip("the sizes do not fit in an int")
+			}
+
+			err := checkAddressable(test.offset, int(test.compressedSize), int(test.uncompressedSize))
+			if test.wantErr != errors.Is(err, ErrorNotAddressable) || !test.wantErr && err != nil {
+				t.Fatalf("got %v, want an error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// TestArchiveIsValid checks the bounds of an archive larger than 4 GiB, the archive is a sparse file.
+func TestArchiveIsValid(t *testing.T) {
+	tests := []struct {
+		name        string
+		archiveSize int64
+		record      *Block3Record
+		want        bool
+	}{
+		{
+			name:        "inside",
+			archiveSize: math.MaxUint32 + 100,
+			record:      &Block3Record{Offset: 200, CompressedSize: 10},
+			want:        true,
+		},
+		{
+			name:        "end past 4 GiB",
+			archiveSize: math.MaxUint32 + 100,
+			record:      &Block3Record{Offset: math.MaxUint32 - 5, CompressedSize: 10},
+			want:        true,
+		},
+		{
+			name:        "largest record",
+			archiveSize: 2 * math.MaxUint32,
+			record:      &Block3Record{Offset: math.MaxUint32, CompressedSize: math.MaxUint32},
+			want:        true,
+		},
+		{
+			name:        "end past the archive",
+			archiveSize: math.MaxUint32 - 1,
+			record:      &Block3Record{Offset: math.MaxUint32 - 5, CompressedSize: 10},
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeTestMnf(t, t.TempDir(), false)
+
+			err := os.Truncate(getArchivePath(path, 0), test.archiveSize)
+			if err != nil {
+				t.Skipf("no sparse file: %s", err)
+			}
+
+			mnfData := mustParse(t, path)
+			defer mnfData.Close()
+
+			archive, err := mnfData.GetArchive(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if archive.GetSize() != test.archiveSize {
+				t.Fatalf("size %d, want %d", archive.GetSize(), test.archiveSize)
+			}
+			if archive.IsValid(test.record) != test.want {
+				t.Fatalf("end %d: got %t, want %t", test.record.End(), !test.want, test.want)
+			}
+		})
+	}
+}
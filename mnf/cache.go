@@ -0,0 +1,336 @@
+package mnf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"github.com/eso-tools/eso-tools/reader"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// cacheVersion is changed with the layout of cacheEntry, the entries of other versions are not used.
+const cacheVersion = 2
+
+// cacheKey tells whether a cache entry belongs to the .mnf file, a patched file changes at least one field. The options
+// changing the result of Parse are part of it, a file parsed with other limits or another flavour is parsed again.
+type cacheKey struct {
+	Version  int
+	Path     string
+	Size     int64
+	ModTime  int64
+	Checksum uint32
+	Limits   reader.Limits
+	Flavour  Flavour
+}
+
+// cacheEntry is the decoded index of a .mnf file. It is written after its cacheKey, so a stale entry is not decoded.
+type cacheEntry struct {
+	Version        uint16
+	ArchiveCount   uint16
+	ArchiveIds     map[uint16]uint16
+	Field5         uint32
+	DataSize       uint32
+	Index0         *Index0
+	Index3         *cacheIndex3
+	SkippedIndexes []*SkippedIndex
+	Warnings       []string
+
+	// Flavour is the detected flavour, FlavourAuto when it is not detected yet
+	Flavour Flavour
+	// FileNames are the ZOSFT file names of FileNamesFlavour, nil when they are not read yet
+	FileNames        map[uint32]string
+	FileNamesFlavour Flavour
+}
+
+type cacheIndex3 struct {
+	Field1 []byte
+	Count1 uint32
+	Count2 uint32
+	Count3 uint32
+
+	UncompressedBlock1Size uint32
+	CompressedBlock1Size   uint32
+	UncompressedBlock2Size uint32
+	CompressedBlock2Size   uint32
+	UncompressedBlock3Size uint32
+	CompressedBlock3Size   uint32
+
+	Block1Indexes           []uint32
+	Block1Flags             []uint8
+	Block2Ids               []uint32
+	Block2Fields            []byte
+	Block3UncompressedSizes []uint32
+	Block3CompressedSizes   []uint32
+	Block3Hashes            []uint32
+	Block3Offsets           []uint32
+	Block3ArchiveIndexes    []uint16
+	Block3CompressionTypes  []uint16
+}
+
+// mnfCache is the cache entry of a parsed .mnf file.
+type mnfCache struct {
+	path string
+	key  cacheKey
+}
+
+func getDefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "eso-tools", "mnf")
+}
+
+// getCachePath returns the file of the cache entry of the .mnf file path, there is one per path.
+func getCachePath(cacheDir string, path string) string {
+	sum := sha256.Sum256([]byte(path))
+
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:16])+".cache")
+}
+
+// parseCached parses the .mnf file path from the cache entry matching its content, the file is parsed and the entry
+// written otherwise. A cache that cannot be read or written is not an error.
+func parseCached(ctx context.Context, path string, options []Option) (*Mnf, error) {
+	opts := getOptions(options)
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &mnfCache{
+		path: getCachePath(opts.cacheDir, absPath),
+		key: cacheKey{
+			Version:  cacheVersion,
+			Path:     absPath,
+			Size:     fileInfo.Size(),
+			ModTime:  fileInfo.ModTime().UnixNano(),
+			Checksum: crc32.Checksum(data, crc32cTable),
+			Limits:   opts.limits,
+			Flavour:  opts.flavour,
+		},
+	}
+
+	entry := cache.read()
+	if entry == nil {
+		mnfData, err := parse(path, reader.NewContextReader(ctx, bytes.NewReader(data)), &dirOpener{mnfPath: path}, options)
+		if err != nil {
+			return nil, err
+		}
+		mnfData.cache = cache
+		mnfData.writeCache()
+
+		return mnfData, nil
+	}
+
+	mnfData := &Mnf{
+		Path:           path,
+		Signature:      signature,
+		Version:        entry.Version,
+		ArchiveCount:   entry.ArchiveCount,
+		ArchiveIds:     entry.ArchiveIds,
+		Field5:         entry.Field5,
+		DataSize:       entry.DataSize,
+		Index0:         entry.Index0,
+		Index3:         entry.Index3.get(),
+		SkippedIndexes: entry.SkippedIndexes,
+		Warnings:       entry.Warnings,
+		archives:       map[uint16]*archiveSlot{},
+		archiveOpener:  &dirOpener{mnfPath: path},
+		archiveOptions: opts,
+		cache:          cache,
+	}
+	for archiveIndex := range mnfData.ArchiveIds {
+		mnfData.archives[archiveIndex] = &archiveSlot{}
+	}
+
+	mnfData.flavour = opts.flavour
+	if mnfData.flavour == FlavourAuto {
+		mnfData.flavour = entry.Flavour
+	}
+
+	if entry.FileNames != nil && entry.FileNamesFlavour == mnfData.flavour {
+		mnfData.fileNames = entry.FileNames
+	}
+
+	return mnfData, nil
+}
+
+// read returns the cache entry when its key matches, nil otherwise.
+func (cache *mnfCache) read() *cacheEntry {
+	f, err := os.Open(cache.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	decoder := gob.NewDecoder(bufio.NewReader(f))
+
+	key := cacheKey{}
+	err = decoder.Decode(&key)
+	if err != nil || key != cache.key {
+		return nil
+	}
+
+	entry := &cacheEntry{}
+	err = decoder.Decode(entry)
+	if err != nil || entry.Index3 == nil || !entry.Index3.isValid() {
+		return nil
+	}
+
+	return entry
+}
+
+// writeCache stores the decoded index, the detected flavour and the file names read so far.
+func (mnfData *Mnf) writeCache() {
+	if mnfData.cache == nil {
+		return
+	}
+
+	entry := &cacheEntry{
+		Version:        mnfData.Version,
+		ArchiveCount:   mnfData.ArchiveCount,
+		ArchiveIds:     mnfData.ArchiveIds,
+		Field5:         mnfData.Field5,
+		DataSize:       mnfData.DataSize,
+		Index0:         mnfData.Index0,
+		Index3:         newCacheIndex3(mnfData.Index3),
+		SkippedIndexes: mnfData.SkippedIndexes,
+		Warnings:       mnfData.Warnings,
+	}
+
+	if mnfData.archiveOptions.flavour == FlavourAuto {
+		entry.Flavour = mnfData.flavour
+	}
+
+	if mnfData.fileNames != nil {
+		entry.FileNames = mnfData.fileNames
+		entry.FileNamesFlavour = mnfData.flavour
+	}
+
+	err := os.MkdirAll(filepath.Dir(mnfData.cache.path), 0777)
+	if err != nil {
+		return
+	}
+
+	writeFileAtomic(mnfData.cache.path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		encoder := gob.NewEncoder(bw)
+
+		err := encoder.Encode(mnfData.cache.key)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.Encode(entry)
+		if err != nil {
+			return err
+		}
+
+		return bw.Flush()
+	})
+}
+
+func newCacheIndex3(index3Data *Index3) *cacheIndex3 {
+	return &cacheIndex3{
+		Field1:                  index3Data.Field1,
+		Count1:                  index3Data.Count1,
+		Count2:                  index3Data.Count2,
+		Count3:                  index3Data.Count3,
+		UncompressedBlock1Size:  index3Data.UncompressedBlock1Size,
+		CompressedBlock1Size:    index3Data.CompressedBlock1Size,
+		UncompressedBlock2Size:  index3Data.UncompressedBlock2Size,
+		CompressedBlock2Size:    index3Data.CompressedBlock2Size,
+		UncompressedBlock3Size:  index3Data.UncompressedBlock3Size,
+		CompressedBlock3Size:    index3Data.CompressedBlock3Size,
+		Block1Indexes:           index3Data.block1.indexes,
+		Block1Flags:             index3Data.block1.flags,
+		Block2Ids:               index3Data.block2.ids,
+		Block2Fields:            index3Data.block2.fields,
+		Block3UncompressedSizes: index3Data.block3.uncompressedSizes,
+		Block3CompressedSizes:   index3Data.block3.compressedSizes,
+		Block3Hashes:            index3Data.block3.hashes,
+		Block3Offsets:           index3Data.block3.offsets,
+		Block3ArchiveIndexes:    index3Data.block3.archiveIndexes,
+		Block3CompressionTypes:  index3Data.block3.compressionTypes,
+	}
+}
+
+// isValid checks that the columns of a block are of the same length.
+func (cacheIndex3Data *cacheIndex3) isValid() bool {
+	if len(cacheIndex3Data.Block1Flags) != len(cacheIndex3Data.Block1Indexes) {
+		return false
+	}
+
+	if len(cacheIndex3Data.Block2Fields) != len(cacheIndex3Data.Block2Ids)*4 {
+		return false
+	}
+
+	count := len(cacheIndex3Data.Block3Offsets)
+	for _, n := range []int{
+		len(cacheIndex3Data.Block3UncompressedSizes),
+		len(cacheIndex3Data.Block3CompressedSizes),
+		len(cacheIndex3Data.Block3Hashes),
+		len(cacheIndex3Data.Block3ArchiveIndexes),
+		len(cacheIndex3Data.Block3CompressionTypes),
+	} {
+		if n != count {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cacheIndex3Data *cacheIndex3) get() *Index3 {
+	return &Index3{
+		Field1:                 cacheIndex3Data.Field1,
+		Count1:                 cacheIndex3Data.Count1,
+		Count2:                 cacheIndex3Data.Count2,
+		Count3:                 cacheIndex3Data.Count3,
+		UncompressedBlock1Size: cacheIndex3Data.UncompressedBlock1Size,
+		CompressedBlock1Size:   cacheIndex3Data.CompressedBlock1Size,
+		block1: block1Table{
+			indexes: cacheIndex3Data.Block1Indexes,
+			flags:   cacheIndex3Data.Block1Flags,
+		},
+		UncompressedBlock2Size: cacheIndex3Data.UncompressedBlock2Size,
+		CompressedBlock2Size:   cacheIndex3Data.CompressedBlock2Size,
+		block2: block2Table{
+			ids:    cacheIndex3Data.Block2Ids,
+			fields: cacheIndex3Data.Block2Fields,
+		},
+		UncompressedBlock3Size: cacheIndex3Data.UncompressedBlock3Size,
+		CompressedBlock3Size:   cacheIndex3Data.CompressedBlock3Size,
+		block3: block3Table{
+			uncompressedSizes: cacheIndex3Data.Block3UncompressedSizes,
+			compressedSizes:   cacheIndex3Data.Block3CompressedSizes,
+			hashes:            cacheIndex3Data.Block3Hashes,
+			offsets:           cacheIndex3Data.Block3Offsets,
+			archiveIndexes:    cacheIndex3Data.Block3ArchiveIndexes,
+			compressionTypes:  cacheIndex3Data.Block3CompressionTypes,
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package mnf
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/eso-tools/eso-tools/reader"
+	"os"
+	"testing"
+)
+
+func TestParseCacheInvalidation(t *testing.T) {
+	path := writeTestMnf(t, t.TempDir(), false)
+	cacheDir := WithCacheDir(t.TempDir())
+
+	mnfData, err := Parse(path, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnfData.Close()
+
+	if mnfData.cache.read() == nil {
+		t.Fatal("the cache entry is not written")
+	}
+
+	// the limits are checked again
+	_, err = Parse(path, cacheDir, WithLimits(reader.Limits{MaxSize: reader.DefaultLimits.MaxSize, MaxCount: 1}))
+	if !errors.Is(err, reader.ErrorLimitExceeded) {
+		t.Fatalf("got %v, want %v", err, reader.ErrorLimitExceeded)
+	}
+
+	// the flavour is not taken from the entry of another flavour
+	mnfData, err = Parse(path, cacheDir, WithFlavour(FlavourDepot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnfData.Close()
+
+	if mnfData.GetFlavour() != FlavourDepot {
+		t.Fatalf("flavour %d, want %d", mnfData.GetFlavour(), FlavourDepot)
+	}
+
+	// a patched file of the same size and modification time is parsed again
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint16(data[4:], 2)
+
+	err = os.WriteFile(path, data, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chtimes(path, fileInfo.ModTime(), fileInfo.ModTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData, err = Parse(path, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnfData.Close()
+
+	if mnfData.Version != 2 {
+		t.Fatalf("got version %d of the cache, want 2", mnfData.Version)
+	}
+}
+
+func TestGetFileNamesCopy(t *testing.T) {
+	mnfData, err := Parse(writeTestMnf(t, t.TempDir(), false), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mnfData.Close()
+
+	fileNames, err := mnfData.GetFileNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileNames[1] = "changed.dds"
+
+	_, ok, err := mnfData.GetFileName(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("the file names of the manifest are changed")
+	}
+}
@@ -0,0 +1,108 @@
+package mnf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"github.com/eso-tools/eso-tools/oodle"
+	"io"
+	"sync"
+)
+
+// DecompressFunc decodes a payload that has uncompressedSize bytes when decoded.
+type DecompressFunc func(data []byte, uncompressedSize uint32) ([]byte, error)
+
+// NewReaderFunc returns a reader of the decoded data of r.
+type NewReaderFunc func(r io.Reader) (io.ReadCloser, error)
+
+type Decompressor struct {
+	Name       string
+	Decompress DecompressFunc
+	// NewReader is optional, Mnf.Open streams the data with it instead of calling Decompress
+	NewReader NewReaderFunc
+}
+
+var decompressorsMu sync.RWMutex
+
+var decompressors = map[uint16]*Decompressor{
+	0: {
+		Name:       "none",
+		Decompress: decompressNone,
+		NewReader:  newReaderNone,
+	},
+	1: {
+		Name:       "zlib",
+		Decompress: decompressZlib,
+		NewReader:  newReaderZlib,
+	},
+	4: {
+		Name:       "oodle",
+		Decompress: decompressOodle,
+	},
+	8: {
+		Name:       "oodle",
+		Decompress: decompressOodle,
+	},
+}
+
+// RegisterDecompressor sets the decompressor used for records of compressionType, replacing the previous one.
+// A nil decompress removes it. Mnf.Open reads records of a registered type in memory.
+func RegisterDecompressor(compressionType uint16, name string, decompress DecompressFunc) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	if decompress == nil {
+		delete(decompressors, compressionType)
+		return
+	}
+
+	decompressors[compressionType] = &Decompressor{
+		Name:       name,
+		Decompress: decompress,
+	}
+}
+
+func GetDecompressor(compressionType uint16) (*Decompressor, bool) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+
+	decompressor, ok := decompressors[compressionType]
+
+	return decompressor, ok
+}
+
+func decompressNone(data []byte, uncompressedSize uint32) ([]byte, error) {
+	return data, nil
+}
+
+func decompressZlib(data []byte, uncompressedSize uint32) ([]byte, error) {
+	zlibReader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zlibReader.Close()
+
+	// a record inflating past its uncompressed size fails instead of allocating the rest
+	inflated, err := io.ReadAll(io.LimitReader(zlibReader, int64(uncompressedSize)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(inflated)) > uint64(uncompressedSize) {
+		return nil, fmt.Errorf("data is larger than %d bytes", uncompressedSize)
+	}
+
+	return inflated, nil
+}
+
+func newReaderNone(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+func newReaderZlib(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func decompressOodle(data []byte, uncompressedSize uint32) ([]byte, error) {
+	return oodle.Decompress(data, int64(uncompressedSize))
+}
@@ -0,0 +1,187 @@
+package mnf
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecompressZlibOverflow(t *testing.T) {
+	payload := bytes.Repeat([]byte("payload "), 1000)
+	compressed, err := compress(payload, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+	for _, uncompressedSize := range []int{len(payload), 10} {
+		err = writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			CompressionType:  1,
+			Data:             compressed,
+			Raw:              true,
+			UncompressedSize: uint32(uncompressedSize),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData := mustParse(t, writer.Path)
+	defer mnfData.Close()
+
+	data, err := mnfData.Read(mnfData.Index3.Block3Record(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("got %d bytes, want %d", len(data), len(payload))
+	}
+
+	_, err = mnfData.Read(mnfData.Index3.Block3Record(1))
+	if err == nil || !strings.Contains(err.Error(), "larger than 10 bytes") {
+		t.Fatalf("got %v, want an error for the data larger than 10 bytes", err)
+	}
+}
+
+func reverseTestData(data []byte, uncompressedSize uint32) ([]byte, error) {
+	reversed := slices.Clone(data)
+	slices.Reverse(reversed)
+
+	return reversed, nil
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	tests := []struct {
+		name            string
+		compressionType uint16
+		register        func()
+		wantName        string
+		// stored is read back through the decompressor when it is set
+		stored []byte
+		want   []byte
+	}{
+		{
+			name:            "none",
+			compressionType: 0,
+			wantName:        "none",
+			stored:          []byte("payload"),
+			want:            []byte("payload"),
+		},
+		{
+			name:            "zlib",
+			compressionType: 1,
+			wantName:        "zlib",
+		},
+		{
+			name:            "oodle",
+			compressionType: 8,
+			wantName:        "oodle",
+		},
+		{
+			name:            "unknown",
+			compressionType: 9,
+			stored:          []byte("payload"),
+		},
+		{
+			name:            "registered",
+			compressionType: 9,
+			register: func() {
+				RegisterDecompressor(9, "reverse", reverseTestData)
+			},
+			wantName: "reverse",
+			stored:   []byte("daolyap"),
+			want:     []byte("payload"),
+		},
+		{
+			name:            "replaced",
+			compressionType: 0,
+			register: func() {
+				RegisterDecompressor(0, "reverse", reverseTestData)
+			},
+			wantName: "reverse",
+			stored:   []byte("daolyap"),
+			want:     []byte("payload"),
+		},
+		{
+			name:            "removed",
+			compressionType: 1,
+			register: func() {
+				RegisterDecompressor(1, "", nil)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous, registered := GetDecompressor(test.compressionType)
+			t.Cleanup(func() {
+				decompressorsMu.Lock()
+				defer decompressorsMu.Unlock()
+
+				delete(decompressors, test.compressionType)
+				if registered {
+					decompressors[test.compressionType] = previous
+				}
+			})
+
+			if test.register != nil {
+				test.register()
+			}
+
+			decompressor, ok := GetDecompressor(test.compressionType)
+			if ok != (test.wantName != "") || ok && decompressor.Name != test.wantName {
+				t.Fatalf("got %v, %t, want %q", decompressor, ok, test.wantName)
+			}
+
+			if test.stored == nil {
+				return
+			}
+
+			writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+			err := writer.Add(&WriteEntry{
+				Record2: &Block2Record{
+					Field2: []byte{0x00, 0x00},
+					Flags:  []byte{0x00, 0x00},
+				},
+				CompressionType:  test.compressionType,
+				Data:             test.stored,
+				Raw:              true,
+				UncompressedSize: uint32(len(test.stored)),
+			})
+			if err == nil {
+				err = writer.Close()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mnfData := mustParse(t, writer.Path)
+			defer mnfData.Close()
+
+			data, err := mnfData.Read(mnfData.Index3.Block3Record(0))
+			if test.want == nil {
+				if err == nil {
+					t.Fatalf("got %q, want an error", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, test.want) {
+				t.Fatalf("got %q, want %q", data, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package mnf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Flavour is the kind of a manifest. It decides which record holds the ZOSFT table and whether the leading records of
+// archive 0 are skipped.
+type Flavour uint8
+
+const (
+	// FlavourAuto detects the flavour from the content, GetFlavour never returns it
+	FlavourAuto Flavour = iota
+	// FlavourGame is game.mnf, its ZOSFT table is id 0x00000000
+	FlavourGame
+	// FlavourDepot is eso.mnf, its ZOSFT table is id 0x00ffffff and the leading records of archive 0 are skipped
+	FlavourDepot
+	// FlavourOther is a manifest without a ZOSFT table, e.g. a voice-over or language manifest
+	FlavourOther
+)
+
+var flavourNames = map[Flavour]string{
+	FlavourAuto:  "auto",
+	FlavourGame:  "game",
+	FlavourDepot: "depot",
+	FlavourOther: "other",
+}
+
+func (flavour Flavour) String() string {
+	name, ok := flavourNames[flavour]
+	if !ok {
+		return fmt.Sprintf("flavour(%d)", uint8(flavour))
+	}
+
+	return name
+}
+
+// ParseFlavour returns the flavour named name, an empty name is FlavourAuto.
+func ParseFlavour(name string) (Flavour, error) {
+	if name == "" {
+		return FlavourAuto, nil
+	}
+
+	for flavour, flavourName := range flavourNames {
+		if flavourName == name {
+			return flavour, nil
+		}
+	}
+
+	return FlavourAuto, fmt.Errorf("unknown flavour %q", name)
+}
+
+func (flavour Flavour) getZosftId() (uint32, bool) {
+	switch flavour {
+	case FlavourGame:
+		return zosftGameId, true
+
+	case FlavourDepot:
+		return zosftDepotId, true
+	}
+
+	return 0, false
+}
+
+// GetFlavour returns the flavour given with WithFlavour or detects it by the first call: the manifest is a game or
+// depot one when the record of their ZOSFT id holds a ZOSFT table. When both of them do, the depot is told by its
+// layout, its leading records are in archive 0 and the others are not.
+func (mnfData *Mnf) GetFlavour() Flavour {
+	mnfData.flavourOnce.Do(func() {
+		if mnfData.flavour == FlavourAuto {
+			mnfData.flavour = mnfData.detectFlavour()
+		}
+	})
+
+	return mnfData.flavour
+}
+
+func (mnfData *Mnf) detectFlavour() Flavour {
+	if mnfData.Index3 == nil {
+		return FlavourOther
+	}
+
+	isGame := mnfData.findZosftRecord(zosftGameId) != nil
+	isDepot := mnfData.findZosftRecord(zosftDepotId) != nil
+
+	switch {
+	case isGame && isDepot:
+		if mnfData.hasDepotLayout() {
+			return FlavourDepot
+		}
+
+		return FlavourGame
+
+	case isGame:
+		return FlavourGame
+
+	case isDepot:
+		return FlavourDepot
+	}
+
+	return FlavourOther
+}
+
+// findZosftRecord returns the first record of id that holds a ZOSFT table.
+func (mnfData *Mnf) findZosftRecord(id uint32) *Block3Record {
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		if mnfData.Index3.block2.ids[i] != id {
+			continue
+		}
+
+		record := mnfData.Index3.Block3Record(i)
+		data, _, err := mnfData.ReadWithHeader(record)
+		if err != nil {
+			continue
+		}
+
+		if bytes.HasPrefix(data, []byte(zosftSignature)) {
+			return record
+		}
+	}
+
+	return nil
+}
+
+func (mnfData *Mnf) hasDepotLayout() bool {
+	archiveIndexes := mnfData.Index3.block3.archiveIndexes
+	if len(archiveIndexes) == 0 || archiveIndexes[0] != 0 {
+		return false
+	}
+
+	for _, archiveIndex := range archiveIndexes {
+		if archiveIndex != 0 {
+			return true
+		}
+	}
+
+	return false
+}
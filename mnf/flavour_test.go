@@ -0,0 +1,137 @@
+package mnf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type flavourTestRecord struct {
+	id           uint32
+	archiveIndex uint16
+	data         string
+}
+
+func writeFlavourTestMnf(t *testing.T, records []flavourTestRecord) string {
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+	for _, record := range records {
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     record.id,
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			ArchiveIndex:    record.archiveIndex,
+			CompressionType: 1,
+			Data:            []byte(record.data),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writer.Path
+}
+
+func TestGetFlavour(t *testing.T) {
+	const table = zosftSignature + " table"
+
+	tests := []struct {
+		name    string
+		records []flavourTestRecord
+		flavour Flavour
+		want    Flavour
+	}{
+		{
+			name:    "game",
+			records: []flavourTestRecord{{id: 1, data: "payload"}, {id: zosftGameId, data: table}},
+			want:    FlavourGame,
+		},
+		{
+			name:    "depot",
+			records: []flavourTestRecord{{id: zosftDepotId, data: table}, {id: 1, archiveIndex: 1, data: "payload"}},
+			want:    FlavourDepot,
+		},
+		{
+			name: "both with the depot layout",
+			records: []flavourTestRecord{
+				{id: zosftDepotId, data: table},
+				{id: zosftGameId, archiveIndex: 1, data: table},
+			},
+			want: FlavourDepot,
+		},
+		{
+			name: "both in archive 0",
+			records: []flavourTestRecord{
+				{id: zosftDepotId, data: table},
+				{id: zosftGameId, data: table},
+			},
+			want: FlavourGame,
+		},
+		{
+			name: "both without archive 0 first",
+			records: []flavourTestRecord{
+				{id: zosftGameId, archiveIndex: 1, data: table},
+				{id: zosftDepotId, data: table},
+			},
+			want: FlavourGame,
+		},
+		{
+			name:    "id of a table without a table",
+			records: []flavourTestRecord{{id: zosftGameId, data: "payload"}},
+			want:    FlavourOther,
+		},
+		{
+			name:    "no table",
+			records: []flavourTestRecord{{id: 1, data: "payload"}},
+			want:    FlavourOther,
+		},
+		{
+			name:    "set",
+			records: []flavourTestRecord{{id: zosftGameId, data: table}},
+			flavour: FlavourOther,
+			want:    FlavourOther,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mnfData := mustParse(t, writeFlavourTestMnf(t, test.records), WithFlavour(test.flavour))
+			defer mnfData.Close()
+
+			flavour := mnfData.GetFlavour()
+			if flavour != test.want {
+				t.Fatalf("got %s, want %s", flavour, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFlavour(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Flavour
+		wantErr bool
+	}{
+		{name: "", want: FlavourAuto},
+		{name: "auto", want: FlavourAuto},
+		{name: "game", want: FlavourGame},
+		{name: "depot", want: FlavourDepot},
+		{name: "other", want: FlavourOther},
+		{name: "unknown", wantErr: true},
+	}
+
+	for _, test := range tests {
+		flavour, err := ParseFlavour(test.name)
+		if (err != nil) != test.wantErr || flavour != test.want {
+			t.Fatalf("%q: got %s, %v, want %s", test.name, flavour, err, test.want)
+		}
+		if err == nil && test.name != "" && flavour.String() != test.name {
+			t.Fatalf("%q: String is %q", test.name, flavour.String())
+		}
+	}
+}
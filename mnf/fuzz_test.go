@@ -0,0 +1,83 @@
+package mnf
+
+import (
+	"bytes"
+	"github.com/eso-tools/eso-tools/reader"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var fuzzLimits = reader.Limits{
+	MaxSize:  1 << 20,
+	MaxCount: 1 << 16,
+}
+
+func FuzzParse(f *testing.F) {
+	path := filepath.Join(f.TempDir(), "game.mnf")
+
+	writer := NewWriter(path)
+	writer.Index0 = &Index0{
+		Field1:     []byte{0x00, 0x00},
+		Block1Data: []byte("block1"),
+		Block2Data: []byte("block2"),
+	}
+	for i := 0; i < 4; i++ {
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     uint32(i),
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			ArchiveIndex:    uint16(i % 2),
+			CompressionType: uint16(i % 2),
+			Data:            []byte("data"),
+		})
+		if err != nil {
+			f.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(data)
+	f.Add([]byte(signature))
+
+	opener := ArchiveOpenerFunc(func(archiveId uint16) (ArchiveFile, error) {
+		return nil, fs.ErrNotExist
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mnfData, err := ParseReader(bytes.NewReader(data), opener, WithLimits(fuzzLimits))
+		if err != nil {
+			return
+		}
+
+		if len(mnfData.Index3.block2.ids) != len(mnfData.Index3.block3.offsets) {
+			t.Fatalf("%d block 2 records, %d block 3 records", len(mnfData.Index3.block2.ids), len(mnfData.Index3.block3.offsets))
+		}
+
+		mnfData.GetFlavour()
+	})
+}
+
+func FuzzParseEntryHeader(f *testing.F) {
+	f.Add([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02, 0x00, 0x00, 0x00, 0x01, 0x03, 0xff})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, ok := ParseEntryHeader(data)
+		if ok && header.Size() > len(data) {
+			t.Fatalf("header of %d bytes in %d bytes", header.Size(), len(data))
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package mnf
+
+import (
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+)
+
+var (
+	ErrorHashMismatch = errors.New("hash mismatch")
+	ErrorUnknownHash  = errors.New("unknown hash algorithm")
+	ErrorSizeMismatch = errors.New("uncompressed size mismatch")
+	ErrorDecompress   = errors.New("decompression failed")
+)
+
+// HashAlgorithm is a candidate for Block3Record.Hash, the algorithm of the game is not identified. Compressed
+// algorithms cover the payload as it is stored in the archive, the others cover the decompressed data including the
+// header removed by Read.
+type HashAlgorithm struct {
+	Name       string
+	Compressed bool
+	Sum        func(data []byte) uint32
+}
+
+var HashAlgorithms = []*HashAlgorithm{
+	{Name: "crc32", Compressed: true, Sum: crc32.ChecksumIEEE},
+	{Name: "crc32", Compressed: false, Sum: crc32.ChecksumIEEE},
+	{Name: "crc32c", Compressed: true, Sum: sumCrc32c},
+	{Name: "crc32c", Compressed: false, Sum: sumCrc32c},
+	{Name: "adler32", Compressed: true, Sum: adler32.Checksum},
+	{Name: "adler32", Compressed: false, Sum: adler32.Checksum},
+	{Name: "fnv1", Compressed: true, Sum: sumFnv1},
+	{Name: "fnv1", Compressed: false, Sum: sumFnv1},
+	{Name: "fnv1a", Compressed: true, Sum: sumFnv1a},
+	{Name: "fnv1a", Compressed: false, Sum: sumFnv1a},
+}
+
+// String returns the name of the algorithm and the data it covers, e.g. "crc32-compressed".
+func (algorithm *HashAlgorithm) String() string {
+	if algorithm.Compressed {
+		return algorithm.Name + "-compressed"
+	}
+
+	return algorithm.Name + "-uncompressed"
+}
+
+// GetHashAlgorithm returns the algorithm of HashAlgorithms whose String is name.
+func GetHashAlgorithm(name string) (*HashAlgorithm, bool) {
+	for _, algorithm := range HashAlgorithms {
+		if algorithm.String() == name {
+			return algorithm, true
+		}
+	}
+
+	return nil, false
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+func sumCrc32c(data []byte) uint32 {
+	return crc32.Checksum(data, crc32cTable)
+}
+
+func sumFnv1(data []byte) uint32 {
+	h := fnv.New32()
+	h.Write(data)
+
+	return h.Sum32()
+}
+
+func sumFnv1a(data []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(data)
+
+	return h.Sum32()
+}
+
+func (algorithm *HashAlgorithm) sum(compressed []byte, data []byte) uint32 {
+	if algorithm.Compressed {
+		return algorithm.Sum(compressed)
+	}
+
+	return algorithm.Sum(data)
+}
+
+// HashAlgorithm returns the algorithm set with WithHashAlgorithm, nil when there is none.
+func (mnfData *Mnf) HashAlgorithm() *HashAlgorithm {
+	return mnfData.archiveOptions.hashAlgorithm
+}
+
+// Verify checks that record lies inside its archive and decompresses to UncompressedSize bytes. Its hash is checked
+// with the algorithm of WithHashAlgorithm, it is not checked without one.
+func (mnfData *Mnf) Verify(record *Block3Record) error {
+	compressed, data, err := mnfData.readForHash(record)
+	if err != nil {
+		return err
+	}
+
+	if uint64(len(data)) != uint64(record.UncompressedSize) {
+		return fmt.Errorf("%w: expected %d, got %d", ErrorSizeMismatch, record.UncompressedSize, len(data))
+	}
+
+	algorithm := mnfData.HashAlgorithm()
+	if algorithm == nil {
+		return nil
+	}
+
+	hash := algorithm.sum(compressed, data)
+	if hash != record.Hash {
+		return fmt.Errorf("%w: expected 0x%08x, got 0x%08x", ErrorHashMismatch, record.Hash, hash)
+	}
+
+	return nil
+}
+
+// readForHash returns the payload of record as stored in the archive and decompressed with its header.
+func (mnfData *Mnf) readForHash(record *Block3Record) ([]byte, []byte, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !archive.IsValid(record) {
+		return nil, nil, ErrorNotValidRecord
+	}
+
+	decompressor, ok := GetDecompressor(record.CompressionType)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unsupported compressionType: %d", ErrorDecompress, record.CompressionType)
+	}
+
+	err = archive.limits.CheckSize(uint64(record.UncompressedSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compressed, err := archive.read(record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := decompressor.Decompress(compressed, record.UncompressedSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s: %s", ErrorDecompress, decompressor.Name, err)
+	}
+
+	return compressed, data, nil
+}
@@ -0,0 +1,88 @@
+package mnf
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashWriteReplace(t *testing.T) {
+	for i, algorithm := range HashAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+			writer.HashAlgorithm = algorithm
+
+			for i, payload := range testPayloads {
+				err := writer.Add(&WriteEntry{
+					Record2: &Block2Record{
+						Id:     uint32(i),
+						Field2: []byte{0x00, 0x00},
+						Flags:  []byte{0x00, 0x00},
+					},
+					CompressionType: uint16(i % 2),
+					Data:            payload,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := writer.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mnfData, err := Parse(writer.Path, WithCacheDir(""), WithHashAlgorithm(algorithm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mnfData.Close()
+
+			record := mnfData.Index3.Block3Record(1)
+			err = mnfData.Replace(record, []byte("replaced payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < mnfData.Index3.Len(); i++ {
+				err = mnfData.Verify(mnfData.Index3.Block3Record(i))
+				if err != nil {
+					t.Fatalf("record %d: %s", i, err)
+				}
+			}
+
+			// the records of the zlib payloads do not match another algorithm
+			other := HashAlgorithms[(i+2)%len(HashAlgorithms)]
+			otherData, err := Parse(writer.Path, WithCacheDir(""), WithHashAlgorithm(other))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer otherData.Close()
+
+			err = otherData.Verify(otherData.Index3.Block3Record(1))
+			if !errors.Is(err, ErrorHashMismatch) {
+				t.Fatalf("%s: got %v, want %v", other, err, ErrorHashMismatch)
+			}
+		})
+	}
+}
+
+func TestGetHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"crc32-compressed", true},
+		{"crc32-uncompressed", true},
+		{"fnv1a-uncompressed", true},
+		{"crc32", false},
+		{"md5-compressed", false},
+	}
+
+	for _, test := range tests {
+		algorithm, ok := GetHashAlgorithm(test.name)
+		if ok != test.ok || ok && algorithm.String() != test.name {
+			t.Fatalf("%s: got %v %t, want %t", test.name, algorithm, ok, test.ok)
+		}
+	}
+}
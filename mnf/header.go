@@ -0,0 +1,133 @@
+package mnf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/eso-tools/eso-tools/reader"
+	"io"
+)
+
+const entryHeaderMinSize = 16
+
+// EntryHeader is found before the payload of some records: a zero uint32 and two sections prefixed with their big-endian
+// uint32 length. The content of the sections is not known.
+type EntryHeader struct {
+	Section1 []byte
+	Section2 []byte
+}
+
+func (header *EntryHeader) Size() int {
+	return 12 + len(header.Section1) + len(header.Section2)
+}
+
+// Bytes returns the header as it is stored before the payload.
+func (header *EntryHeader) Bytes() []byte {
+	data := make([]byte, 4, header.Size())
+	data = binary.BigEndian.AppendUint32(data, uint32(len(header.Section1)))
+	data = append(data, header.Section1...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(header.Section2)))
+
+	return append(data, header.Section2...)
+}
+
+// ParseEntryHeader parses the header at the start of data. It reports false when data is shorter than 16 bytes, does
+// not start with a zero uint32 or the sections do not fit in data.
+func ParseEntryHeader(data []byte) (*EntryHeader, bool) {
+	headerSize, err := getHeaderSize(bytes.NewReader(data), int64(len(data)))
+	if err != nil || headerSize == 0 {
+		return nil, false
+	}
+
+	cursor := 8 + uint64(binary.BigEndian.Uint32(data[4:8]))
+
+	return &EntryHeader{
+		Section1: data[8:cursor],
+		Section2: data[cursor+4 : headerSize],
+	}, true
+}
+
+// getHeaderSize returns the size of the header at the start of a payload of size bytes, 0 when there is none. It is
+// the one check of Read, Open and OpenSection, so they agree on which payloads have a header.
+func getHeaderSize(r io.ReaderAt, size int64) (int64, error) {
+	if size < entryHeaderMinSize {
+		return 0, nil
+	}
+
+	data := make([]byte, 8)
+	_, err := r.ReadAt(data, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if binary.BigEndian.Uint32(data[0:4]) != 0 {
+		return 0, nil
+	}
+
+	cursor := 8 + int64(binary.BigEndian.Uint32(data[4:8]))
+	if cursor+4 > size {
+		return 0, nil
+	}
+
+	_, err = r.ReadAt(data[0:4], cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor += 4 + int64(binary.BigEndian.Uint32(data[0:4]))
+	if cursor > size {
+		return 0, nil
+	}
+
+	return cursor, nil
+}
+
+type entryReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (entryReader *entryReader) Close() error {
+	return entryReader.closer.Close()
+}
+
+// skipHeader returns r without the header of a payload of size bytes. Only the bytes looked at by getHeaderSize are
+// kept in memory, they are read again when there is no header.
+func skipHeader(r io.Reader, size int64) (io.Reader, error) {
+	prefix := &prefixReader{
+		r: r,
+	}
+
+	headerSize, err := getHeaderSize(prefix, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if headerSize > int64(len(prefix.data)) {
+		_, err = io.CopyN(io.Discard, r, headerSize-int64(len(prefix.data)))
+		if err != nil {
+			return nil, err
+		}
+		headerSize = int64(len(prefix.data))
+	}
+
+	return io.MultiReader(bytes.NewReader(prefix.data[headerSize:]), r), nil
+}
+
+// prefixReader gives random access to the start of a stream, the bytes up to the end of the last ReadAt are kept.
+type prefixReader struct {
+	r    io.Reader
+	data []byte
+}
+
+func (prefix *prefixReader) ReadAt(p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+	if end > int64(len(prefix.data)) {
+		data, err := reader.ReadBytes(prefix.r, int(end-int64(len(prefix.data))))
+		prefix.data = append(prefix.data, data...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return copy(p, prefix.data[off:end]), nil
+}
@@ -0,0 +1,134 @@
+package mnf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func makeTestHeader(section1 []byte, section2Size uint32, section2 []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, 0)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(section1)))
+	data = append(data, section1...)
+	data = binary.BigEndian.AppendUint32(data, section2Size)
+
+	return append(data, section2...)
+}
+
+func TestEntryHeaderReadOpen(t *testing.T) {
+	payload := bytes.Repeat([]byte("payload "), 10)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{
+			name: "header",
+			data: append(makeTestHeader([]byte("section1"), 8, []byte("section2")), payload...),
+			want: payload,
+		},
+		{
+			name: "empty sections",
+			data: append(makeTestHeader(nil, 0, nil), payload...),
+			want: payload,
+		},
+		{
+			name: "oversized section1",
+			data: append(binary.BigEndian.AppendUint32(make([]byte, 4), 1000), payload...),
+		},
+		{
+			name: "oversized section2",
+			data: append(makeTestHeader([]byte("section1"), 1000, nil), payload...),
+		},
+		{
+			name: "not zero",
+			data: append([]byte{0x00, 0x00, 0x00, 0x01}, payload...),
+		},
+		{
+			name: "short",
+			data: makeTestHeader(nil, 0, nil),
+		},
+	}
+
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+	for i, test := range tests {
+		for compressionType := range 2 {
+			err := writer.Add(&WriteEntry{
+				Record2: &Block2Record{
+					Id:     uint32(i),
+					Field2: []byte{0x00, 0x00},
+					Flags:  []byte{0x00, 0x00},
+				},
+				CompressionType: uint16(compressionType),
+				Data:            test.data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData, err := Parse(writer.Path, WithCacheDir(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mnfData.Close()
+
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		test := tests[i/2]
+		record := mnfData.Index3.Block3Record(i)
+
+		t.Run(fmt.Sprintf("%s/compression=%d", test.name, record.CompressionType), func(t *testing.T) {
+			want := test.want
+			if want == nil {
+				want = test.data
+			}
+
+			data, err := mnfData.Read(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("Read: got %q, want %q", data, want)
+			}
+
+			r, err := mnfData.Open(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err = io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("Open: got %q, want %q", data, want)
+			}
+
+			if record.CompressionType != 0 {
+				return
+			}
+
+			section, err := mnfData.OpenSection(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err = io.ReadAll(section)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("OpenSection: got %q, want %q", data, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package mnf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/eso-tools/eso-tools/reader"
+	"io"
+	"slices"
+)
+
+// SkippedIndex is an index of an unknown id left out by Parse.
+type SkippedIndex struct {
+	Id uint16
+	// Offset is counted from the start of the .mnf file
+	Offset int64
+	Size   int64
+}
+
+func (mnfData *Mnf) addWarning(format string, args ...any) {
+	mnfData.Warnings = append(mnfData.Warnings, fmt.Sprintf(format, args...))
+}
+
+// indexReader decodes the index following its id into mnfData.
+type indexReader func(mnfData *Mnf, d *reader.Decoder, limits reader.Limits) error
+
+// latestVersion is the newest version with a known layout, the manifests of other versions are parsed with it.
+const latestVersion = 3
+
+// indexReaders are the decoders of the indexes of each known version by index id.
+var indexReaders = map[uint16]map[uint16]indexReader{
+	3: {
+		0: (*Mnf).parseIndex0,
+		3: (*Mnf).parseIndex3,
+	},
+}
+
+func getIndexReaders(version uint16) (map[uint16]indexReader, bool) {
+	readers, ok := indexReaders[version]
+	if !ok {
+		return indexReaders[latestVersion], false
+	}
+
+	return readers, true
+}
+
+// parseIndexes reads the indexes until EOF with the decoders of Version. Unknown indexes are skipped by skipIndex and
+// reported in SkippedIndexes. A manifest without Index3 is an error.
+func (mnfData *Mnf) parseIndexes(d *reader.Decoder, limits reader.Limits) error {
+	start := d.Offset()
+
+	var end int64
+	if mnfData.DataSize != 0 {
+		end = start + int64(mnfData.DataSize)
+	}
+
+	readers, _ := getIndexReaders(mnfData.Version)
+
+	for {
+		offset := d.Offset()
+
+		indexId, err := d.ReadUint16("IndexId", binary.BigEndian)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			mnfData.addWarning("trailing byte at %d is skipped", offset)
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		read, ok := readers[indexId]
+		if ok {
+			err = read(mnfData, d, limits)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		d, err = mnfData.skipIndex(d, indexId, offset, end, readers, limits)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mnfData.DataSize != 0 && d.Offset()-start != int64(mnfData.DataSize) {
+		mnfData.addWarning("index data is %d bytes, DataSize is %d", d.Offset()-start, mnfData.DataSize)
+	}
+
+	if mnfData.Index3 == nil {
+		return d.ErrorAt("Index3", d.Offset(), ErrorMissingIndex3)
+	}
+
+	return nil
+}
+
+func (mnfData *Mnf) parseIndex0(d *reader.Decoder, limits reader.Limits) error {
+	offset := d.Offset() - 2
+
+	index0Data, err := readIndex0(d, limits)
+	if err != nil {
+		return err
+	}
+
+	if mnfData.Index0 != nil {
+		mnfData.addWarning("index 0 is repeated at %d", offset)
+	}
+	mnfData.Index0 = index0Data
+
+	return nil
+}
+
+func (mnfData *Mnf) parseIndex3(d *reader.Decoder, limits reader.Limits) error {
+	offset := d.Offset() - 2
+
+	index3Data, err := readIndex3(d, limits, mnfData.addWarning)
+	if err != nil {
+		return err
+	}
+
+	if mnfData.Index3 != nil {
+		mnfData.addWarning("index 3 is repeated at %d", offset)
+	}
+	mnfData.Index3 = index3Data
+
+	return nil
+}
+
+// skipIndex skips the index of an unknown id at offset and returns the Decoder of the data after it. The size of an
+// index is not stored, so the index data up to end, or to EOF when end is 0, is read and probed for the next known
+// index: the first offset from which the known indexes decode up to the end of the data. Without one the rest of the
+// data is skipped.
+func (mnfData *Mnf) skipIndex(d *reader.Decoder, indexId uint16, offset int64, end int64, readers map[uint16]indexReader, limits reader.Limits) (*reader.Decoder, error) {
+	start := d.Offset()
+
+	var r io.Reader = d
+	if end > start {
+		r = io.LimitReader(d, end-start)
+	}
+
+	data, err := limits.ReadAll(r)
+	if err != nil {
+		return nil, d.ErrorAt("Index", start, err)
+	}
+
+	next := probeIndexes(data, start, readers, limits)
+
+	mnfData.SkippedIndexes = append(mnfData.SkippedIndexes, &SkippedIndex{
+		Id:     indexId,
+		Offset: offset,
+		Size:   start + int64(next) - offset,
+	})
+	mnfData.addWarning("unknown index %d of %d bytes at %d is skipped", indexId, start+int64(next)-offset, offset)
+
+	if end > start && start+int64(len(data)) < end {
+		mnfData.addWarning("index %d is truncated", indexId)
+	}
+
+	return reader.NewDecoderAt(io.MultiReader(bytes.NewReader(data[next:]), d), "mnf", start+int64(next)), nil
+}
+
+// probeIndexes returns the first offset of data from which the indexes of readers decode up to the end of data, or
+// len(data) when there is none. base is the offset of data in the file.
+func probeIndexes(data []byte, base int64, readers map[uint16]indexReader, limits reader.Limits) int {
+	// valid keeps the offsets already probed, a chain of indexes is only decoded once
+	valid := map[int]bool{len(data): true}
+
+	for i := 0; i < len(data); i++ {
+		var chain []int
+		ok := false
+
+		for next := i; ; {
+			known, probed := valid[next]
+			if probed {
+				ok = known
+				break
+			}
+
+			chain = append(chain, next)
+
+			next, known = probeIndex(data, next, base, readers, limits)
+			if !known {
+				break
+			}
+		}
+
+		for _, offset := range chain {
+			valid[offset] = ok
+		}
+
+		if ok {
+			return i
+		}
+	}
+
+	return len(data)
+}
+
+// probeIndex decodes the known index at offset i of data without warnings and returns the offset after it.
+func probeIndex(data []byte, i int, base int64, readers map[uint16]indexReader, limits reader.Limits) (int, bool) {
+	if i+2 > len(data) {
+		return 0, false
+	}
+
+	read, ok := readers[binary.BigEndian.Uint16(data[i:])]
+	if !ok {
+		return 0, false
+	}
+
+	start := base + int64(i) + 2
+	d := reader.NewDecoderAt(bytes.NewReader(data[i+2:]), "mnf", start)
+
+	probe := &Mnf{}
+	err := read(probe, d, limits)
+	if err != nil || len(probe.Warnings) > 0 {
+		return 0, false
+	}
+
+	// an empty Index0 is made of zeros, a run of zeros is not taken for an index
+	next := i + 2 + int(d.Offset()-start)
+	if !slices.ContainsFunc(data[i:next], func(b byte) bool { return b != 0 }) {
+		return 0, false
+	}
+
+	return next, true
+}
+
+func readIndex0(d *reader.Decoder, limits reader.Limits) (*Index0, error) {
+	index0Data := &Index0{}
+	field1, err := d.ReadBytes("Index0.Field1", 2)
+	if err != nil {
+		return nil, err
+	}
+	index0Data.Field1 = field1
+
+	block1Size, err := d.ReadUint32("Index0.Block1Size", binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	index0Data.Block1Size = block1Size
+
+	err = limits.CheckSize(uint64(index0Data.Block1Size))
+	if err != nil {
+		return nil, d.Error("Index0.Block1Size", err)
+	}
+
+	block1Data, err := d.ReadBytes("Index0.Block1Data", int(index0Data.Block1Size))
+	if err != nil {
+		return nil, err
+	}
+	index0Data.Block1Data = block1Data
+
+	block2Size, err := d.ReadUint32("Index0.Block2Size", binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	index0Data.Block2Size = block2Size
+
+	err = limits.CheckSize(uint64(index0Data.Block2Size))
+	if err != nil {
+		return nil, d.Error("Index0.Block2Size", err)
+	}
+
+	block2Data, err := d.ReadBytes("Index0.Block2Data", int(index0Data.Block2Size))
+	if err != nil {
+		return nil, err
+	}
+	index0Data.Block2Data = block2Data
+
+	return index0Data, nil
+}
+
+// readIndex3 decodes Index3, the counts that do not match are reported to warn and the rows of both blocks 2 and 3 are
+// kept, see Index3.Len.
+func readIndex3(d *reader.Decoder, limits reader.Limits, warn func(format string, args ...any)) (*Index3, error) {
+	index3Data := &Index3{}
+	field1, err := d.ReadBytes("Index3.Field1", 4)
+	if err != nil {
+		return nil, err
+	}
+	index3Data.Field1 = field1
+
+	count1, err := d.ReadUint32("Index3.Count1", binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	index3Data.Count1 = count1
+
+	count2, err := d.ReadUint32("Index3.Count2", binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	index3Data.Count2 = count2
+
+	count3, err := d.ReadUint32("Index3.Count3", binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	index3Data.Count3 = count3
+
+	if index3Data.Count2 != index3Data.Count3 {
+		warn("%s", d.Error("Index3.Count3", fmt.Errorf("count2 %d does not match count3 %d", index3Data.Count2, index3Data.Count3)))
+	}
+
+	index3Data.UncompressedBlock1Size, index3Data.CompressedBlock1Size, err = readIndex3Block(d, "Index3.Block1Records", index3Data.Count1, block1RecordSize, limits, warn, index3Data.block1.decode)
+	if err != nil {
+		return nil, err
+	}
+
+	index3Data.UncompressedBlock2Size, index3Data.CompressedBlock2Size, err = readIndex3Block(d, "Index3.Block2Records", index3Data.Count2, block2RecordSize, limits, warn, index3Data.block2.decode)
+	if err != nil {
+		return nil, err
+	}
+
+	index3Data.UncompressedBlock3Size, index3Data.CompressedBlock3Size, err = readIndex3Block(d, "Index3.Block3Records", index3Data.Count3, block3RecordSize, limits, warn, index3Data.block3.decode)
+	if err != nil {
+		return nil, err
+	}
+
+	return index3Data, nil
+}
+
+// readIndex3Block reads the sizes of a block and decompresses its count records of recordSize at once for decode. The
+// errors in the compressed data are reported at the offset of the block. A size that does not match count is reported
+// to warn, decode gets the records that fit in it.
+func readIndex3Block(d *reader.Decoder, field string, count uint32, recordSize uint32, limits reader.Limits, warn func(format string, args ...any), decode func(data []byte)) (uint32, uint32, error) {
+	uncompressedSize, err := d.ReadUint32(field+".UncompressedSize", binary.BigEndian)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	compressedSize, err := d.ReadUint32(field+".CompressedSize", binary.BigEndian)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = checkBlock(count, uncompressedSize, limits)
+	if err != nil {
+		return 0, 0, d.Error(field, err)
+	}
+
+	if uint64(count)*uint64(recordSize) != uint64(uncompressedSize) {
+		warn("%s", d.Error(field, fmt.Errorf("%d records of %d bytes do not match size %d", count, recordSize, uncompressedSize)))
+	}
+
+	offset := d.Offset()
+	lr := io.LimitReader(d, int64(compressedSize))
+
+	zr, err := zlib.NewReader(lr)
+	if err != nil {
+		return 0, 0, d.ErrorAt(field, offset, err)
+	}
+	defer zr.Close()
+
+	data, err := reader.ReadBytes(zr, int(uncompressedSize))
+	if err != nil {
+		return 0, 0, d.ErrorAt(field, offset, err)
+	}
+
+	decode(data)
+
+	// the rest of the block is not part of the next field
+	_, err = io.Copy(io.Discard, lr)
+	if err != nil {
+		return 0, 0, d.ErrorAt(field, offset, err)
+	}
+
+	return uncompressedSize, compressedSize, nil
+}
+
+// checkBlock checks count and uncompressedSize of a block against limits.
+func checkBlock(count uint32, uncompressedSize uint32, limits reader.Limits) error {
+	err := limits.CheckCount(uint64(count))
+	if err != nil {
+		return err
+	}
+
+	return limits.CheckSize(uint64(uncompressedSize))
+}
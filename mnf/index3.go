@@ -0,0 +1,190 @@
+package mnf
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The records of Index3 are kept by column, a record is only built when it is asked for.
+
+type block1Table struct {
+	indexes []uint32
+	flags   []uint8
+}
+
+type block2Table struct {
+	ids []uint32
+	// fields holds Field2 and Flags of each record, 4 bytes per record
+	fields []byte
+}
+
+type block3Table struct {
+	uncompressedSizes []uint32
+	compressedSizes   []uint32
+	hashes            []uint32
+	offsets           []uint32
+	archiveIndexes    []uint16
+	compressionTypes  []uint16
+}
+
+// Len returns the number of records of the blocks 2 and 3, a row of both is a stored file.
+func (index3Data *Index3) Len() int {
+	return min(len(index3Data.block2.ids), len(index3Data.block3.offsets))
+}
+
+func (index3Data *Index3) Block1Len() int {
+	return len(index3Data.block1.indexes)
+}
+
+func (index3Data *Index3) Block1Record(i int) *Block1Record {
+	return &Block1Record{
+		Index: index3Data.block1.indexes[i],
+		Flag:  index3Data.block1.flags[i],
+	}
+}
+
+// Block2Record returns the record of row i. Field2 and Flags share the memory of the index.
+func (index3Data *Index3) Block2Record(i int) *Block2Record {
+	fields := index3Data.block2.fields[i*4 : i*4+4 : i*4+4]
+
+	return &Block2Record{
+		Id:     index3Data.block2.ids[i],
+		Field2: fields[0:2:2],
+		Flags:  fields[2:4:4],
+		row:    i + 1,
+	}
+}
+
+// Block3Record returns a copy of the record of row i, changing it does not change the index.
+func (index3Data *Index3) Block3Record(i int) *Block3Record {
+	return &Block3Record{
+		UncompressedSize: index3Data.block3.uncompressedSizes[i],
+		CompressedSize:   index3Data.block3.compressedSizes[i],
+		Hash:             index3Data.block3.hashes[i],
+		Offset:           index3Data.block3.offsets[i],
+		ArchiveIndex:     index3Data.block3.archiveIndexes[i],
+		CompressionType:  index3Data.block3.compressionTypes[i],
+		row:              i + 1,
+	}
+}
+
+func (index3Data *Index3) setBlock3Record(i int, record *Block3Record) {
+	index3Data.block3.uncompressedSizes[i] = record.UncompressedSize
+	index3Data.block3.compressedSizes[i] = record.CompressedSize
+	index3Data.block3.hashes[i] = record.Hash
+	index3Data.block3.offsets[i] = record.Offset
+	index3Data.block3.archiveIndexes[i] = record.ArchiveIndex
+	index3Data.block3.compressionTypes[i] = record.CompressionType
+}
+
+func (index3Data *Index3) addBlock1Record(record *Block1Record) {
+	index3Data.block1.indexes = append(index3Data.block1.indexes, record.Index&0xffffff)
+	index3Data.block1.flags = append(index3Data.block1.flags, record.Flag)
+}
+
+// checkBlock2Record checks that record fits in a row of block 2.
+func checkBlock2Record(record *Block2Record) error {
+	if len(record.Field2) != 2 || len(record.Flags) != 2 {
+		return fmt.Errorf("not valid record 0x%08x: Field2 and Flags must be 2 bytes long", record.Id)
+	}
+
+	return nil
+}
+
+// addRecord adds a row of the blocks 2 and 3.
+func (index3Data *Index3) addRecord(record2 *Block2Record, record3 *Block3Record) error {
+	err := checkBlock2Record(record2)
+	if err != nil {
+		return err
+	}
+
+	index3Data.block2.ids = append(index3Data.block2.ids, record2.Id)
+	index3Data.block2.fields = append(index3Data.block2.fields, record2.Field2...)
+	index3Data.block2.fields = append(index3Data.block2.fields, record2.Flags...)
+
+	index3Data.block3.uncompressedSizes = append(index3Data.block3.uncompressedSizes, record3.UncompressedSize)
+	index3Data.block3.compressedSizes = append(index3Data.block3.compressedSizes, record3.CompressedSize)
+	index3Data.block3.hashes = append(index3Data.block3.hashes, record3.Hash)
+	index3Data.block3.offsets = append(index3Data.block3.offsets, record3.Offset)
+	index3Data.block3.archiveIndexes = append(index3Data.block3.archiveIndexes, record3.ArchiveIndex)
+	index3Data.block3.compressionTypes = append(index3Data.block3.compressionTypes, record3.CompressionType)
+
+	return nil
+}
+
+func (table *block1Table) decode(data []byte) {
+	count := len(data) / int(block1RecordSize)
+	table.indexes = make([]uint32, count)
+	table.flags = make([]uint8, count)
+
+	for i := 0; i < count; i++ {
+		recordData := data[i*int(block1RecordSize):]
+		table.indexes[i] = binary.LittleEndian.Uint32(recordData) & 0xffffff
+		table.flags[i] = recordData[3]
+	}
+}
+
+func (table *block1Table) encode() []byte {
+	data := make([]byte, 0, len(table.indexes)*int(block1RecordSize))
+	for i, index := range table.indexes {
+		data = binary.LittleEndian.AppendUint32(data, index&0xffffff|uint32(table.flags[i])<<24)
+	}
+
+	return data
+}
+
+func (table *block2Table) decode(data []byte) {
+	count := len(data) / int(block2RecordSize)
+	table.ids = make([]uint32, count)
+	table.fields = make([]byte, count*4)
+
+	for i := 0; i < count; i++ {
+		recordData := data[i*int(block2RecordSize):]
+		table.ids[i] = binary.LittleEndian.Uint32(recordData[0:4])
+		copy(table.fields[i*4:i*4+4], recordData[4:8])
+	}
+}
+
+func (table *block2Table) encode() []byte {
+	data := make([]byte, 0, len(table.ids)*int(block2RecordSize))
+	for i, id := range table.ids {
+		data = binary.LittleEndian.AppendUint32(data, id)
+		data = append(data, table.fields[i*4:i*4+4]...)
+	}
+
+	return data
+}
+
+func (table *block3Table) decode(data []byte) {
+	count := len(data) / int(block3RecordSize)
+	table.uncompressedSizes = make([]uint32, count)
+	table.compressedSizes = make([]uint32, count)
+	table.hashes = make([]uint32, count)
+	table.offsets = make([]uint32, count)
+	table.archiveIndexes = make([]uint16, count)
+	table.compressionTypes = make([]uint16, count)
+
+	for i := 0; i < count; i++ {
+		recordData := data[i*int(block3RecordSize):]
+		table.uncompressedSizes[i] = binary.LittleEndian.Uint32(recordData[0:4])
+		table.compressedSizes[i] = binary.LittleEndian.Uint32(recordData[4:8])
+		table.hashes[i] = binary.LittleEndian.Uint32(recordData[8:12])
+		table.offsets[i] = binary.LittleEndian.Uint32(recordData[12:16])
+		table.archiveIndexes[i] = binary.LittleEndian.Uint16(recordData[16:18])
+		table.compressionTypes[i] = binary.LittleEndian.Uint16(recordData[18:20])
+	}
+}
+
+func (table *block3Table) encode() []byte {
+	data := make([]byte, 0, len(table.offsets)*int(block3RecordSize))
+	for i := range table.offsets {
+		data = binary.LittleEndian.AppendUint32(data, table.uncompressedSizes[i])
+		data = binary.LittleEndian.AppendUint32(data, table.compressedSizes[i])
+		data = binary.LittleEndian.AppendUint32(data, table.hashes[i])
+		data = binary.LittleEndian.AppendUint32(data, table.offsets[i])
+		data = binary.LittleEndian.AppendUint16(data, table.archiveIndexes[i])
+		data = binary.LittleEndian.AppendUint16(data, table.compressionTypes[i])
+	}
+
+	return data
+}
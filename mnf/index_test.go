@@ -0,0 +1,243 @@
+package mnf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// writeIndexBenchmarkMnf writes a .mnf file of count small records, the index is most of the parse time.
+func writeIndexBenchmarkMnf(b *testing.B, count int) string {
+	path := filepath.Join(b.TempDir(), "index.mnf")
+
+	writer := NewWriter(path)
+	data := []byte{0x00}
+	for i := 0; i < count; i++ {
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     uint32(i),
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			ArchiveIndex:     uint16(i % 4),
+			Hash:             uint32(i),
+			Data:             data,
+			Raw:              true,
+			UncompressedSize: 1,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return path
+}
+
+// BenchmarkParse parses a .mnf file, its archives are not opened. retained-B is the heap kept by the parsed file. With
+// cache=true the index is read from a cache entry written before the timer starts, the file is still read and
+// checksummed.
+func BenchmarkParse(b *testing.B) {
+	for _, cache := range []bool{false, true} {
+		for _, count := range []int{10000, 100000, 1000000} {
+			b.Run(fmt.Sprintf("cache=%t/records=%d", cache, count), func(b *testing.B) {
+				path := writeIndexBenchmarkMnf(b, count)
+
+				options := []Option{WithCacheDir("")}
+				if cache {
+					options = []Option{WithCacheDir(b.TempDir())}
+
+					_, err := Parse(path, options...)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					mnfData, err := Parse(path, options...)
+					if err != nil {
+						b.Fatal(err)
+					}
+					mnfData.Close()
+				}
+
+				b.StopTimer()
+				b.ReportMetric(float64(getRetainedHeap(b, path, options)), "retained-B")
+			})
+		}
+	}
+}
+
+func getRetainedHeap(b *testing.B, path string, options []Option) int64 {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	mnfData, err := Parse(path, options...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(mnfData)
+
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
+
+// testHeaderSize is the size of the header of writeTestMnf, the index data follows it.
+const testHeaderSize = 20
+
+// patchTestMnf returns the .mnf file of writeTestMnf without Index0 with index inserted at offset of the index data,
+// or appended when offset is negative.
+func patchTestMnf(t *testing.T, offset int, index []byte) []byte {
+	data, err := os.ReadFile(writeTestMnf(t, t.TempDir(), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexData := slices.Clone(data[testHeaderSize:])
+	if offset < 0 {
+		offset = len(indexData)
+	}
+	indexData = slices.Insert(indexData, offset, index...)
+
+	header := slices.Clone(data[:testHeaderSize])
+	binary.LittleEndian.PutUint32(header[testHeaderSize-4:], uint32(len(indexData)))
+
+	return append(header, indexData...)
+}
+
+func TestParseUnknownIndex(t *testing.T) {
+	unknownIndex := []byte{0x00, 0x07, 0x01, 0x02, 0x03, 0x04, 0x05}
+	zeroIndex := append([]byte{0x00, 0x07}, make([]byte, 64)...)
+
+	tests := []struct {
+		name   string
+		offset int
+		index  []byte
+	}{
+		{"after Index3", -1, unknownIndex},
+		{"before Index3", 0, unknownIndex},
+		{"zeros before Index3", 0, zeroIndex},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := patchTestMnf(t, test.offset, test.index)
+			mnfData, err := ParseReader(bytes.NewReader(data), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mnfData.Index3.Len() != len(testPayloads) {
+				t.Fatalf("%d records, want %d", mnfData.Index3.Len(), len(testPayloads))
+			}
+
+			if mnfData.Index0 != nil {
+				t.Fatal("the unknown index is taken for Index0")
+			}
+
+			offset := int64(testHeaderSize + test.offset)
+			if test.offset < 0 {
+				offset = int64(len(data) - len(test.index))
+			}
+
+			want := &SkippedIndex{Id: 7, Offset: offset, Size: int64(len(test.index))}
+			if len(mnfData.SkippedIndexes) != 1 || *mnfData.SkippedIndexes[0] != *want {
+				t.Fatalf("skipped indexes: %+v, want %+v", mnfData.SkippedIndexes, want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	data := patchTestMnf(t, -1, nil)
+	binary.LittleEndian.PutUint16(data[4:], 2)
+
+	// an unknown version is parsed with the latest layout
+	mnfData, err := ParseReader(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mnfData.Version != 2 || mnfData.Index3.Len() != len(testPayloads) || len(mnfData.Warnings) != 1 {
+		t.Fatalf("version %d, %d records, warnings %q", mnfData.Version, mnfData.Index3.Len(), mnfData.Warnings)
+	}
+
+	// it is not supported when the layout does not fit
+	_, err = ParseReader(bytes.NewReader(data[:testHeaderSize+10]), nil)
+	if !errors.Is(err, ErrorNotSupportedVersion) {
+		t.Fatalf("got %v, want %v", err, ErrorNotSupportedVersion)
+	}
+}
+
+func TestParseIndex3Warnings(t *testing.T) {
+	// the counts of Index3 follow the index id and Field1
+	const count2Offset = testHeaderSize + 2 + 4 + 4
+	const count3Offset = count2Offset + 4
+
+	addCount := func(data []byte, offset int) {
+		binary.BigEndian.PutUint32(data[offset:], binary.BigEndian.Uint32(data[offset:])+1)
+	}
+
+	tests := []struct {
+		name     string
+		patch    func(data []byte)
+		warnings int
+	}{
+		{
+			name:  "valid",
+			patch: func(data []byte) {},
+		},
+		{
+			name: "count2 does not match count3",
+			patch: func(data []byte) {
+				addCount(data, count3Offset)
+			},
+			// count3 does not match the size of block 3 either
+			warnings: 2,
+		},
+		{
+			name: "counts do not match the blocks",
+			patch: func(data []byte) {
+				addCount(data, count2Offset)
+				addCount(data, count3Offset)
+			},
+			warnings: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := patchTestMnf(t, -1, nil)
+			test.patch(data)
+
+			mnfData, err := ParseReader(bytes.NewReader(data), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(mnfData.Warnings) != test.warnings {
+				t.Fatalf("warnings %q, want %d", mnfData.Warnings, test.warnings)
+			}
+			if mnfData.Index3.Len() != len(testPayloads) {
+				t.Fatalf("%d records, want %d", mnfData.Index3.Len(), len(testPayloads))
+			}
+		})
+	}
+}
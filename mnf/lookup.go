@@ -0,0 +1,121 @@
+package mnf
+
+import (
+	"errors"
+)
+
+// lookupIndex maps the records by id, Field2 and Flags. The records of Index3 block 1 are not used, their meaning is
+// not known.
+type lookupIndex struct {
+	records map[uint64]int
+	// named keeps the first main record of an id, it gets the ZOSFT file name of the id
+	named map[uint32]int
+	// ids keeps every record of an id, skipped ones included
+	ids     map[uint32][]int
+	skipped map[int]bool
+}
+
+func getLookupKey(id uint32, field2 []byte, flags []byte) (uint64, bool) {
+	if len(field2) != 2 || len(flags) != 2 {
+		return 0, false
+	}
+
+	return uint64(id)<<32 | uint64(field2[0])<<24 | uint64(field2[1])<<16 | uint64(flags[0])<<8 | uint64(flags[1]), true
+}
+
+func (mnfData *Mnf) getLookupIndex() *lookupIndex {
+	mnfData.lookupOnce.Do(func() {
+		mnfData.lookupIndex = mnfData.buildLookupIndex()
+	})
+
+	return mnfData.lookupIndex
+}
+
+// buildLookupIndex keeps the records Records would return: the leading records of a depot and the
+// records outside of their archive are skipped. The records of missing archives are kept, like by Records they do not
+// get the file name.
+func (mnfData *Mnf) buildLookupIndex() *lookupIndex {
+	index := &lookupIndex{
+		records: map[uint64]int{},
+		named:   map[uint32]int{},
+		ids:     map[uint32][]int{},
+		skipped: map[int]bool{},
+	}
+
+	if mnfData.Index3 == nil {
+		return index
+	}
+
+	rowCount := mnfData.Index3.Len()
+
+	isDepot := mnfData.IsDepot()
+	skip := isDepot
+
+	for i := 0; i < rowCount; i++ {
+		record2 := mnfData.Index3.Block2Record(i)
+		record3 := mnfData.Index3.Block3Record(i)
+
+		index.ids[record2.Id] = append(index.ids[record2.Id], i)
+
+		if isDepot && skip && record3.ArchiveIndex != 0 {
+			skip = false
+		}
+
+		if skip {
+			index.skipped[i] = true
+			continue
+		}
+
+		archive, err := mnfData.GetArchive(record3.ArchiveIndex)
+		missing := errors.Is(err, ErrorMissingArchive)
+		if !missing && (err != nil || !archive.IsValid(record3)) {
+			index.skipped[i] = true
+			continue
+		}
+
+		key, ok := getLookupKey(record2.Id, record2.Field2, record2.Flags)
+		if !ok {
+			index.skipped[i] = true
+			continue
+		}
+
+		_, ok = index.records[key]
+		if !ok {
+			index.records[key] = i
+		}
+
+		_, ok = index.named[record2.Id]
+		if !ok && !missing && record2.isMainRecord() {
+			index.named[record2.Id] = i
+		}
+	}
+
+	return index
+}
+
+// Lookup returns the first record with id, field2 and flags. A record of a missing archive is returned, reading it
+// fails with ErrorMissingArchive. The index is built by the first call.
+func (mnfData *Mnf) Lookup(id uint32, field2 []byte, flags []byte) (*Block2Record, *Block3Record, bool) {
+	key, ok := getLookupKey(id, field2, flags)
+	if !ok {
+		return nil, nil, false
+	}
+
+	i, ok := mnfData.getLookupIndex().records[key]
+	if !ok {
+		return nil, nil, false
+	}
+
+	return mnfData.Index3.Block2Record(i), mnfData.Index3.Block3Record(i), true
+}
+
+// IsNamed reports whether record is the one that gets the ZOSFT file name of its id, record must be returned by the
+// index.
+func (mnfData *Mnf) IsNamed(record *Block2Record) bool {
+	i, ok := mnfData.getLookupIndex().named[record.Id]
+	if !ok {
+		return false
+	}
+
+	return record.row == i+1
+}
@@ -0,0 +1,185 @@
+package mnf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// lookupTestRecords are stored uncompressed by writeLookupTestMnf, the record of id 2 in archive 1 is out of its archive
+// and archive 3 is missing.
+var lookupTestRecords = []struct {
+	id           uint32
+	field2       []byte
+	archiveIndex uint16
+	data         string
+}{
+	{id: 1, field2: []byte{0x00, 0x00}, archiveIndex: 0, data: "a"},
+	{id: 1, field2: []byte{0x00, 0x01}, archiveIndex: 0, data: "b"},
+	{id: 1, field2: []byte{0x00, 0x00}, archiveIndex: 1, data: "c"},
+	{id: 2, field2: []byte{0x00, 0x00}, archiveIndex: 1, data: "d"},
+	{id: 2, field2: []byte{0x00, 0x00}, archiveIndex: 2, data: "e"},
+	{id: 3, field2: []byte{0x00, 0x00}, archiveIndex: 3, data: "f"},
+}
+
+func writeLookupTestMnf(t *testing.T) string {
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+	for _, record := range lookupTestRecords {
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     record.id,
+				Field2: record.field2,
+				Flags:  []byte{0x00, 0x00},
+			},
+			ArchiveIndex: record.archiveIndex,
+			Data:         []byte(record.data),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Truncate(getArchivePath(writer.Path, 1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(getArchivePath(writer.Path, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writer.Path
+}
+
+func TestLookup(t *testing.T) {
+	path := writeLookupTestMnf(t)
+
+	tests := []struct {
+		name    string
+		flavour Flavour
+		id      uint32
+		field2  []byte
+		flags   []byte
+		wantRow int
+		wantOk  bool
+	}{
+		{
+			name:    "first of duplicates",
+			flavour: FlavourOther,
+			id:      1,
+			wantRow: 0,
+			wantOk:  true,
+		},
+		{
+			name:    "variant",
+			flavour: FlavourOther,
+			id:      1,
+			field2:  []byte{0x00, 0x01},
+			wantRow: 1,
+			wantOk:  true,
+		},
+		{
+			name:    "out of its archive",
+			flavour: FlavourOther,
+			id:      2,
+			wantRow: 4,
+			wantOk:  true,
+		},
+		{
+			name:    "missing archive",
+			flavour: FlavourOther,
+			id:      3,
+			wantRow: 5,
+			wantOk:  true,
+		},
+		{
+			name:    "unknown id",
+			flavour: FlavourOther,
+			id:      4,
+		},
+		{
+			name:    "unknown flags",
+			flavour: FlavourOther,
+			id:      1,
+			flags:   []byte{0x00, 0x01},
+		},
+		{
+			name:    "not valid field2",
+			flavour: FlavourOther,
+			id:      1,
+			field2:  []byte{0x00},
+		},
+		{
+			name:    "leading depot records",
+			flavour: FlavourDepot,
+			id:      1,
+			wantRow: 2,
+			wantOk:  true,
+		},
+		{
+			name:    "leading depot variant",
+			flavour: FlavourDepot,
+			id:      1,
+			field2:  []byte{0x00, 0x01},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mnfData := mustParse(t, path, WithFlavour(test.flavour))
+			defer mnfData.Close()
+
+			field2 := test.field2
+			if field2 == nil {
+				field2 = []byte{0x00, 0x00}
+			}
+			flags := test.flags
+			if flags == nil {
+				flags = []byte{0x00, 0x00}
+			}
+
+			record2, record3, ok := mnfData.Lookup(test.id, field2, flags)
+			if ok != test.wantOk {
+				t.Fatalf("got %t, want %t", ok, test.wantOk)
+			}
+			if !ok {
+				return
+			}
+
+			want2 := mnfData.Index3.Block2Record(test.wantRow)
+			want3 := mnfData.Index3.Block3Record(test.wantRow)
+			if record2.Id != want2.Id || *record3 != *want3 {
+				t.Fatalf("got %+v, want the row %d %+v", record3, test.wantRow, want3)
+			}
+		})
+	}
+}
+
+func TestLookupMissingArchive(t *testing.T) {
+	path := writeTestMnf(t, t.TempDir(), false)
+
+	// archive 1 holds the records 1 and 3
+	err := os.Remove(getArchivePath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData := mustParse(t, path)
+	defer mnfData.Close()
+
+	_, record3, ok := mnfData.Lookup(1, []byte{0x00, 0x00}, []byte{0x00, 0x00})
+	if !ok {
+		t.Fatal("the record of the missing archive is not found")
+	}
+
+	_, err = mnfData.Read(record3)
+	if !errors.Is(err, ErrorMissingArchive) {
+		t.Fatalf("got %v, want %v", err, ErrorMissingArchive)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package mnf
+
+import (
+	"os"
+)
+
+func mmap(file *os.File) ([]byte, error) {
+	return nil, nil
+}
+
+func munmap(data []byte) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package mnf
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps file read-only. It returns nil for the files that cannot be mapped, they are read with ReadAt.
+func mmap(file *os.File) ([]byte, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := fi.Size()
+	if size == 0 || size != int64(int(size)) {
+		return nil, nil
+	}
+
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
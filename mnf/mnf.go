@@ -3,16 +3,19 @@ package mnf
 import (
 	"bufio"
 	"bytes"
-	"compress/zlib"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/eso-tools/eso-tools/reader"
 	"github.com/eso-tools/eso-tools/zosft"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 const signature = "MES2"
@@ -22,11 +25,15 @@ const (
 	block3RecordSize uint32 = 20
 )
 
-var ErrorNotValidRecord = errors.New("not valid record")
+var (
+	ErrorNotValidRecord      = errors.New("not valid record")
+	ErrorMissingArchive      = errors.New("missing archive")
+	ErrorNotSupportedVersion = errors.New("not supported version")
+	ErrorMissingIndex3       = errors.New("index 3 is missing")
+)
 
 type Mnf struct {
-	Path     string
-	Archives map[uint16]*Archive
+	Path string
 
 	Signature    string
 	Version      uint16
@@ -36,16 +43,84 @@ type Mnf struct {
 	DataSize     uint32
 	Index0       *Index0
 	Index3       *Index3
+
+	// SkippedIndexes and Warnings report what Parse did not understand
+	SkippedIndexes []*SkippedIndex
+	Warnings       []string
+
+	lookupOnce  sync.Once
+	lookupIndex *lookupIndex
+
+	flavourOnce sync.Once
+	flavour     Flavour
+
+	fileNamesOnce sync.Once
+	fileNames     map[uint32]string
+	fileNamesErr  error
+
+	// cache is the cache entry of a manifest parsed with Parse, it is nil when the cache is disabled
+	cache *mnfCache
+
+	// archives are opened by the first GetArchive call
+	archives       map[uint16]*archiveSlot
+	archiveOpener  ArchiveOpener
+	archiveOptions *options
+}
+
+// Parse parses the .mnf file path. Its decoded index is kept in a cache, see WithCacheDir, and is used again as long
+// as the file does not change.
+func Parse(path string, options ...Option) (*Mnf, error) {
+	return ParseContext(context.Background(), path, options...)
+}
+
+// ParseContext is Parse stopping with the error of ctx once ctx is done.
+func ParseContext(ctx context.Context, path string, options ...Option) (*Mnf, error) {
+	if getOptions(options).cacheDir != "" {
+		return parseCached(ctx, path, options)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parse(path, reader.NewContextReader(ctx, f), &dirOpener{mnfPath: path}, options)
+}
+
+// ParseFS parses the .mnf file name of fsys, its archives are opened from fsys as with NewFSArchiveOpener. The archives
+// read into memory are bounded by the limits of WithLimits.
+func ParseFS(fsys fs.FS, name string, options ...Option) (*Mnf, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parse(name, f, newFSOpener(fsys, name, getOptions(options).limits), options)
+}
+
+// ParseReader parses the .mnf data read from r, its archives are opened with archiveOpener. Path is empty and
+// Replace is not supported.
+func ParseReader(r io.Reader, archiveOpener ArchiveOpener, options ...Option) (*Mnf, error) {
+	return parse("", r, archiveOpener, options)
 }
 
-func Parse(path string) (*Mnf, error) {
+func parse(path string, r io.Reader, archiveOpener ArchiveOpener, options []Option) (*Mnf, error) {
 	mnf := &Mnf{
-		Path:     path,
-		Archives: map[uint16]*Archive{},
+		Path:           path,
+		archives:       map[uint16]*archiveSlot{},
+		archiveOpener:  archiveOpener,
+		archiveOptions: getOptions(options),
 	}
 
-	err := mnf.parse()
+	mnf.flavour = mnf.archiveOptions.flavour
+
+	err := mnf.parse(r)
 	if err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
 		return nil, err
 	}
 
@@ -70,15 +145,15 @@ type Index3 struct {
 
 	UncompressedBlock1Size uint32
 	CompressedBlock1Size   uint32
-	Block1Records          []*Block1Record
+	block1                 block1Table
 
 	UncompressedBlock2Size uint32
 	CompressedBlock2Size   uint32
-	Block2Records          []*Block2Record
+	block2                 block2Table
 
 	UncompressedBlock3Size uint32
 	CompressedBlock3Size   uint32
-	Block3Records          []*Block3Record
+	block3                 block3Table
 }
 
 type Block1Record struct {
@@ -90,6 +165,8 @@ type Block2Record struct {
 	Id     uint32
 	Field2 []byte
 	Flags  []byte
+	// row is the row of the record in Index3 plus one, 0 for a record that is not returned by Index3
+	row int
 }
 
 type Block3Record struct {
@@ -99,40 +176,43 @@ type Block3Record struct {
 	Offset           uint32
 	ArchiveIndex     uint16
 	CompressionType  uint16
+	// row is the row of the record in Index3 plus one, 0 for a record that is not returned by Index3
+	row int
+}
+
+// End returns the offset after the data of the record, it does not overflow like Offset+CompressedSize.
+func (record *Block3Record) End() int64 {
+	return int64(record.Offset) + int64(record.CompressedSize)
 }
 
-func (mnfData *Mnf) parse() error {
+func (mnfData *Mnf) parse(rd io.Reader) error {
 	var data []byte
 	var err error
 
-	f, err := os.Open(mnfData.Path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	r := bufio.NewReaderSize(f, 1024*1024)
+	d := reader.NewDecoder(bufio.NewReaderSize(rd, 1024*1024), "mnf")
 
-	data, err = reader.ReadBytes(r, len([]byte(signature)))
+	data, err = d.ReadBytes("Signature", len([]byte(signature)))
 	if err != nil {
 		return err
 	}
 
 	if string(data) != signature {
-		return errors.New("wrong signature")
+		return d.Error("Signature", errors.New("wrong signature"))
 	}
 	mnfData.Signature = signature
 
-	version, err := reader.ReadUint16(r, binary.LittleEndian)
+	version, err := d.ReadUint16("Version", binary.LittleEndian)
 	if err != nil {
 		return err
 	}
-	if version != 3 {
-		return errors.New("not supported version")
-	}
 	mnfData.Version = version
 
-	archiveCount, err := reader.ReadUint16(r, binary.LittleEndian)
+	_, known := getIndexReaders(version)
+	if !known {
+		mnfData.addWarning("the layout of version %d is not known, it is parsed as version %d", version, latestVersion)
+	}
+
+	archiveCount, err := d.ReadUint16("ArchiveCount", binary.LittleEndian)
 	if err != nil {
 		return err
 	}
@@ -140,7 +220,7 @@ func (mnfData *Mnf) parse() error {
 
 	archiveIds := make(map[uint16]uint16, mnfData.ArchiveCount)
 	for i := uint16(0); i < mnfData.ArchiveCount; i++ {
-		value, err := reader.ReadUint16(r, binary.LittleEndian)
+		value, err := d.ReadUint16("ArchiveIds", binary.LittleEndian)
 		if err != nil {
 			return err
 		}
@@ -148,281 +228,258 @@ func (mnfData *Mnf) parse() error {
 	}
 	mnfData.ArchiveIds = archiveIds
 
-	for archiveIndex, archiveId := range mnfData.ArchiveIds {
-		baseName := filepath.Base(mnfData.Path)
-		archivePath := filepath.Join(filepath.Dir(mnfData.Path), fmt.Sprintf("%s%04d.dat", strings.TrimSuffix(baseName, filepath.Ext(baseName)), archiveId))
-		archive, err := NewArchive(archivePath)
-		if err != nil {
-			return err
-		}
-
-		mnfData.Archives[archiveIndex] = archive
+	for archiveIndex := range mnfData.ArchiveIds {
+		mnfData.archives[archiveIndex] = &archiveSlot{}
 	}
 
-	field5, err := reader.ReadUint32(r, binary.LittleEndian)
+	field5, err := d.ReadUint32("Field5", binary.LittleEndian)
 	if err != nil {
 		return err
 	}
 	mnfData.Field5 = field5
 
-	dataSize, err := reader.ReadUint32(r, binary.LittleEndian)
+	dataSize, err := d.ReadUint32("DataSize", binary.LittleEndian)
 	if err != nil {
 		return err
 	}
 	mnfData.DataSize = dataSize
 
 	// indexes
-	indexId, err := reader.ReadUint16(r, binary.BigEndian)
+	err = mnfData.parseIndexes(d, mnfData.archiveOptions.limits)
+	if err != nil && !known {
+		return fmt.Errorf("%w %d: %w", ErrorNotSupportedVersion, version, err)
+	}
 	if err != nil {
 		return err
 	}
 
-	if indexId == 0 {
-		index0Data := &Index0{}
-		field1, err := reader.ReadBytes(r, 2)
-		if err != nil {
-			return err
-		}
-		index0Data.Field1 = field1
+	return nil
+}
 
-		block1Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index0Data.Block1Size = block1Size
+type archiveSlot struct {
+	once    sync.Once
+	archive *Archive
+	err     error
+}
 
-		block1Data, err := reader.ReadBytes(r, int(index0Data.Block1Size))
-		if err != nil {
-			return err
-		}
-		index0Data.Block1Data = block1Data
+// GetArchive returns the archive of archiveIndex, it is opened by the first call. A missing .dat file is reported by
+// every call with ErrorMissingArchive, the records of the other archives can still be read.
+func (mnfData *Mnf) GetArchive(archiveIndex uint16) (*Archive, error) {
+	slot, ok := mnfData.archives[archiveIndex]
+	if !ok {
+		return nil, fmt.Errorf("not valid archiveIndex: %d", archiveIndex)
+	}
 
-		block2Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index0Data.Block2Size = block2Size
+	slot.once.Do(func() {
+		slot.archive, slot.err = mnfData.openArchive(mnfData.ArchiveIds[archiveIndex])
+	})
 
-		block2Data, err := reader.ReadBytes(r, int(index0Data.Block2Size))
-		if err != nil {
-			return err
-		}
-		index0Data.Block2Data = block2Data
+	return slot.archive, slot.err
+}
 
-		mnfData.Index0 = index0Data
+func (mnfData *Mnf) openArchive(archiveId uint16) (*Archive, error) {
+	file, err := mnfData.archiveOpener.OpenArchive(archiveId)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrorMissingArchive, err)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		indexId, err = reader.ReadUint16(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
+	archive, err := newArchive(file, mnfData.archiveOptions)
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
 
-	if indexId == 3 {
-		index3Data := &Index3{
-			Block1Records: []*Block1Record{},
-			Block2Records: []*Block2Record{},
-			Block3Records: []*Block3Record{},
-		}
-		field1, err := reader.ReadBytes(r, 4)
-		if err != nil {
-			return err
-		}
-		index3Data.Field1 = field1
+	return archive, nil
+}
 
-		count1, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
+// Close closes the archives opened so far. The Mnf must not be read after it.
+func (mnfData *Mnf) Close() error {
+	var errs []error
+	for _, slot := range mnfData.archives {
+		if slot.archive == nil {
+			continue
 		}
-		index3Data.Count1 = count1
 
-		count2, err := reader.ReadUint32(r, binary.BigEndian)
+		err := slot.archive.Close()
 		if err != nil {
-			return err
+			errs = append(errs, err)
 		}
-		index3Data.Count2 = count2
+	}
 
-		count3, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.Count3 = count3
+	return errors.Join(errs...)
+}
 
-		uncompressedBlock1Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.UncompressedBlock1Size = uncompressedBlock1Size
+func getArchivePath(mnfPath string, archiveId uint16) string {
+	baseName := filepath.Base(mnfPath)
+	return filepath.Join(filepath.Dir(mnfPath), fmt.Sprintf("%s%04d.dat", strings.TrimSuffix(baseName, filepath.Ext(baseName)), archiveId))
+}
 
-		compressedBlock1Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.CompressedBlock1Size = compressedBlock1Size
+func (mnfData *Mnf) Read(record *Block3Record) ([]byte, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, err
+	}
 
-		lr := io.LimitReader(r, int64(index3Data.CompressedBlock1Size))
-		zr, err := zlib.NewReader(lr)
-		if err != nil {
-			return err
-		}
+	if !archive.IsValid(record) {
+		return nil, ErrorNotValidRecord
+	}
 
-		var recordSize uint32
-
-		block1Records := []*Block1Record{}
-		recordSize = block1RecordSize
-		for i := uint32(0); i < index3Data.UncompressedBlock1Size/recordSize; i++ {
-			recordData, err := reader.ReadBytes(zr, int(recordSize))
-			if err != nil {
-				return err
-			}
-			record := &Block1Record{
-				Index: binary.LittleEndian.Uint32(recordData) & 0xffffff,
-				Flag:  recordData[3:4][0],
-			}
-
-			block1Records = append(block1Records, record)
-		}
-		index3Data.Block1Records = block1Records
-		zr.Close()
+	data, err := archive.Read(record)
+	if err != nil {
+		return nil, err
+	}
 
-		uncompressedBlock2Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.UncompressedBlock2Size = uncompressedBlock2Size
+	return data, nil
+}
 
-		compressedBlock2Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.CompressedBlock2Size = compressedBlock2Size
+func (mnfData *Mnf) ReadWithDecompressor(record *Block3Record) ([]byte, *Decompressor, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		lr = io.LimitReader(r, int64(index3Data.CompressedBlock2Size))
-		zr, err = zlib.NewReader(lr)
-		if err != nil {
-			return err
-		}
+	if !archive.IsValid(record) {
+		return nil, nil, ErrorNotValidRecord
+	}
 
-		block2Records := []*Block2Record{}
-		recordSize = block2RecordSize
-		for i := uint32(0); i < index3Data.UncompressedBlock2Size/recordSize; i++ {
-			recordData, err := reader.ReadBytes(zr, int(recordSize))
-			if err != nil {
-				return err
-			}
-			record := &Block2Record{
-				Id:     binary.LittleEndian.Uint32(recordData[0:4]),
-				Field2: recordData[4:6],
-				Flags:  recordData[6:8],
-			}
-
-			block2Records = append(block2Records, record)
-		}
-		index3Data.Block2Records = block2Records
-		zr.Close()
+	return archive.ReadWithDecompressor(record)
+}
 
-		uncompressedBlock3Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.UncompressedBlock3Size = uncompressedBlock3Size
+// ReadWithHeader returns the payload of record and its header, see Archive.ReadWithHeader.
+func (mnfData *Mnf) ReadWithHeader(record *Block3Record) ([]byte, *EntryHeader, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		compressedBlock3Size, err := reader.ReadUint32(r, binary.BigEndian)
-		if err != nil {
-			return err
-		}
-		index3Data.CompressedBlock3Size = compressedBlock3Size
+	if !archive.IsValid(record) {
+		return nil, nil, ErrorNotValidRecord
+	}
 
-		lr = io.LimitReader(r, int64(index3Data.CompressedBlock3Size))
-		zr, err = zlib.NewReader(lr)
-		if err != nil {
-			return err
-		}
+	return archive.ReadWithHeader(record)
+}
 
-		block3Records := []*Block3Record{}
-		recordSize = block3RecordSize
-		for i := uint32(0); i < index3Data.UncompressedBlock3Size/recordSize; i++ {
-			recordData, err := reader.ReadBytes(zr, int(recordSize))
-			if err != nil {
-				return err
-			}
-			record := &Block3Record{
-				UncompressedSize: binary.LittleEndian.Uint32(recordData[0:4]),
-				CompressedSize:   binary.LittleEndian.Uint32(recordData[4:8]),
-				Hash:             binary.LittleEndian.Uint32(recordData[8:12]),
-				Offset:           binary.LittleEndian.Uint32(recordData[12:16]),
-				ArchiveIndex:     binary.LittleEndian.Uint16(recordData[16:18]),
-				CompressionType:  binary.LittleEndian.Uint16(recordData[18:20]),
-			}
-			block3Records = append(block3Records, record)
-		}
-		index3Data.Block3Records = block3Records
-		zr.Close()
+func (mnfData *Mnf) ReadRaw(record *Block3Record) ([]byte, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if !archive.IsValid(record) {
+		return nil, ErrorNotValidRecord
+	}
 
-		mnfData.Index3 = index3Data
+	data, err := archive.ReadRaw(record)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return data, nil
 }
 
-func (mnfData *Mnf) Read(record *Block3Record) ([]byte, error) {
-	archive, ok := mnfData.Archives[record.ArchiveIndex]
-	if !ok {
-		return nil, fmt.Errorf("not valid archiveIndex: %d", record.ArchiveIndex)
+// Open streams the data of record, see Archive.Open.
+func (mnfData *Mnf) Open(record *Block3Record) (io.ReadCloser, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, err
 	}
 
 	if !archive.IsValid(record) {
 		return nil, ErrorNotValidRecord
 	}
 
-	data, err := archive.Read(record)
+	return archive.Open(record)
+}
+
+// ReadContext is Read failing with the error of ctx once ctx is done, a read already started is not interrupted.
+func (mnfData *Mnf) ReadContext(ctx context.Context, record *Block3Record) ([]byte, error) {
+	err := ctx.Err()
 	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	return mnfData.Read(record)
 }
 
-func (mnfData *Mnf) ReadRaw(record *Block3Record) ([]byte, error) {
-	archive, ok := mnfData.Archives[record.ArchiveIndex]
-	if !ok {
-		return nil, fmt.Errorf("not valid archiveIndex: %d", record.ArchiveIndex)
+// OpenContext is Open with a reader failing with the error of ctx once ctx is done, so a copy of a large record stops
+// in the middle.
+func (mnfData *Mnf) OpenContext(ctx context.Context, record *Block3Record) (io.ReadCloser, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := mnfData.Open(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contextReadCloser{
+		Reader: reader.NewContextReader(ctx, r),
+		Closer: r,
+	}, nil
+}
+
+type contextReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// OpenSection gives random access to the data of an uncompressed record, see Archive.OpenSection.
+func (mnfData *Mnf) OpenSection(record *Block3Record) (*io.SectionReader, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
+	if err != nil {
+		return nil, err
 	}
 
 	if !archive.IsValid(record) {
 		return nil, ErrorNotValidRecord
 	}
 
-	data, err := archive.ReadRaw(record)
+	return archive.OpenSection(record)
+}
+
+// Size returns the size of the data returned by Read, see Archive.Size.
+func (mnfData *Mnf) Size(record *Block3Record) (int64, error) {
+	archive, err := mnfData.GetArchive(record.ArchiveIndex)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return data, nil
+	if !archive.IsValid(record) {
+		return 0, ErrorNotValidRecord
+	}
+
+	return archive.Size(record)
 }
 
+const zosftSignature = "ZOSFT"
+
 var zosftDepotId uint32 = 0x00ffffff // filetable.dat
 var zosftGameId uint32 = 0x00000000
 var anftDepotId uint32 = 0x01000000 // animsfiletable.dat
 
+// GetZosft returns the ZOSFT table of a game or depot manifest, it is nil for the other flavours.
 func (mnfData *Mnf) GetZosft() (*zosft.Zosft, error) {
-	var zosftRecord *Block3Record
-	for i, record := range mnfData.Index3.Block2Records {
-		if (mnfData.IsDepot() && record.Id == zosftDepotId) || (mnfData.IsGame() && record.Id == zosftGameId) {
-			zosftRecord = mnfData.Index3.Block3Records[i]
-			break
-		}
+	zosftId, ok := mnfData.GetFlavour().getZosftId()
+	if !ok || mnfData.Index3 == nil {
+		return nil, nil
 	}
 
+	zosftRecord := mnfData.findZosftRecord(zosftId)
 	if zosftRecord == nil {
 		return nil, nil
 	}
 
-	data, err := mnfData.Read(zosftRecord)
+	data, _, err := mnfData.ReadWithHeader(zosftRecord)
 	if err != nil {
 		return nil, err
 	}
 
-	zosftData, err := zosft.Parse(bytes.NewReader(data))
+	zosftData, err := zosft.ParseWithLimits(bytes.NewReader(data), mnfData.archiveOptions.limits)
 	if err != nil {
 		return nil, err
 	}
@@ -430,12 +487,57 @@ func (mnfData *Mnf) GetZosft() (*zosft.Zosft, error) {
 	return zosftData, nil
 }
 
+// GetFileNames returns a copy of the ZOSFT file names by id, they are empty for a manifest without a ZOSFT table. They
+// are kept in the cache of the manifest.
+func (mnfData *Mnf) GetFileNames() (map[uint32]string, error) {
+	fileNames, err := mnfData.getFileNames()
+	if err != nil {
+		return nil, err
+	}
+
+	return maps.Clone(fileNames), nil
+}
+
+// GetFileName returns the ZOSFT file name of id, see GetFileNames.
+func (mnfData *Mnf) GetFileName(id uint32) (string, bool, error) {
+	fileNames, err := mnfData.getFileNames()
+	if err != nil {
+		return "", false, err
+	}
+
+	fileName, ok := fileNames[id]
+
+	return fileName, ok, nil
+}
+
+// getFileNames returns the file names shared by the callers, they must not be modified.
+func (mnfData *Mnf) getFileNames() (map[uint32]string, error) {
+	mnfData.fileNamesOnce.Do(func() {
+		if mnfData.fileNames != nil {
+			return
+		}
+
+		zosftData, err := mnfData.GetZosft()
+		if err != nil {
+			mnfData.fileNamesErr = err
+			return
+		}
+
+		mnfData.fileNames = map[uint32]string{}
+		if zosftData != nil {
+			mnfData.fileNames = zosftData.GetFileNamesById()
+		}
+
+		mnfData.writeCache()
+	})
+
+	return mnfData.fileNames, mnfData.fileNamesErr
+}
+
 func (mnfData *Mnf) IsDepot() bool {
-	baseName := filepath.Base(mnfData.Path)
-	return baseName == "eso.mnf"
+	return mnfData.GetFlavour() == FlavourDepot
 }
 
 func (mnfData *Mnf) IsGame() bool {
-	baseName := filepath.Base(mnfData.Path)
-	return baseName == "game.mnf"
+	return mnfData.GetFlavour() == FlavourGame
 }
@@ -0,0 +1,171 @@
+package mnf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/eso-tools/eso-tools/reader"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// testPayloads are stored by writeTestMnf in two archives, uncompressed and with zlib.
+var testPayloads = [][]byte{
+	[]byte("first payload"),
+	bytes.Repeat([]byte("second payload "), 100),
+	[]byte("third payload"),
+	bytes.Repeat([]byte{0x00}, 1000),
+}
+
+// writeTestMnf writes a .mnf file of testPayloads with ids 0 to 3, with Index0 when withIndex0 is set.
+func writeTestMnf(t testing.TB, dir string, withIndex0 bool) string {
+	path := filepath.Join(dir, "game.mnf")
+
+	writer := NewWriter(path)
+	if withIndex0 {
+		writer.Index0 = &Index0{
+			Field1:     []byte{0x00, 0x01},
+			Block1Data: []byte("block1"),
+			Block2Data: []byte("block2"),
+		}
+	}
+
+	for i, payload := range testPayloads {
+		err := writer.Add(&WriteEntry{
+			Record2: &Block2Record{
+				Id:     uint32(i),
+				Field2: []byte{0x00, 0x00},
+				Flags:  []byte{0x00, 0x00},
+			},
+			ArchiveIndex:    uint16(i % 2),
+			CompressionType: uint16(i / 2 % 2),
+			Data:            payload,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParseFormatError(t *testing.T) {
+	data := patchTestMnf(t, -1, nil)
+
+	badSignature := bytes.Clone(data)
+	badSignature[0] = 'X'
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantField  string
+		wantOffset int64
+	}{
+		{
+			name:       "signature",
+			data:       badSignature,
+			wantField:  "Signature",
+			wantOffset: 0,
+		},
+		{
+			name:       "short header",
+			data:       data[:7],
+			wantField:  "ArchiveCount",
+			wantOffset: 6,
+		},
+		{
+			name:       "short Index3",
+			data:       data[:30],
+			wantField:  "Index3.Count2",
+			wantOffset: 30,
+		},
+		{
+			name:       "short block",
+			data:       data[:60],
+			wantField:  "Index3.Block1Records",
+			wantOffset: 46,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseReader(bytes.NewReader(test.data), nil)
+
+			var formatError *reader.FormatError
+			if !errors.As(err, &formatError) {
+				t.Fatalf("got %v, want a *reader.FormatError", err)
+			}
+			if formatError.Format != "mnf" || formatError.Field != test.wantField || formatError.Offset != test.wantOffset {
+				t.Fatalf("got %s at %d, want %s at %d", formatError.Field, formatError.Offset, test.wantField, test.wantOffset)
+			}
+		})
+	}
+}
+
+func TestOpenContext(t *testing.T) {
+	mnfData := mustParse(t, writeTestMnf(t, t.TempDir(), false))
+	defer mnfData.Close()
+
+	tests := []struct {
+		name        string
+		cancelOpen  bool
+		cancelAfter int
+		wantOpenErr error
+		wantErr     error
+	}{
+		{name: "not canceled", cancelAfter: -1},
+		{name: "canceled before open", cancelOpen: true, wantOpenErr: context.Canceled},
+		{name: "canceled before read", cancelAfter: 0, wantErr: context.Canceled},
+		{name: "canceled during copy", cancelAfter: 10, wantErr: context.Canceled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if test.cancelOpen {
+				cancel()
+			}
+
+			r, err := mnfData.OpenContext(ctx, mnfData.Index3.Block3Record(1))
+			if !errors.Is(err, test.wantOpenErr) || test.wantOpenErr == nil && err != nil {
+				t.Fatalf("open: got %v, want %v", err, test.wantOpenErr)
+			}
+			if err != nil {
+				return
+			}
+			defer r.Close()
+
+			var data []byte
+			if test.cancelAfter >= 0 {
+				data = make([]byte, test.cancelAfter)
+				_, err = io.ReadFull(r, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				cancel()
+			}
+
+			rest, err := io.ReadAll(r)
+			if !errors.Is(err, test.wantErr) || test.wantErr == nil && err != nil {
+				t.Fatalf("read: got %v, want %v", err, test.wantErr)
+			}
+
+			data = append(data, rest...)
+			if test.wantErr == nil && !bytes.Equal(data, testPayloads[1]) {
+				t.Fatalf("got %d bytes, want %d", len(data), len(testPayloads[1]))
+			}
+			if test.wantErr != nil && len(data) != test.cancelAfter {
+				t.Fatalf("got %d bytes after cancel, want %d", len(data), test.cancelAfter)
+			}
+		})
+	}
+}
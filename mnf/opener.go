@@ -0,0 +1,114 @@
+package mnf
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eso-tools/eso-tools/reader"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveFile is an opened .dat file, *os.File implements it.
+type ArchiveFile interface {
+	io.ReaderAt
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// ArchiveOpener opens the .dat file of an archive id. An archive that does not exist is reported with an error
+// matching fs.ErrNotExist.
+type ArchiveOpener interface {
+	OpenArchive(archiveId uint16) (ArchiveFile, error)
+}
+
+type ArchiveOpenerFunc func(archiveId uint16) (ArchiveFile, error)
+
+func (opener ArchiveOpenerFunc) OpenArchive(archiveId uint16) (ArchiveFile, error) {
+	return opener(archiveId)
+}
+
+// dirOpener opens the .dat files next to a .mnf file on disk.
+type dirOpener struct {
+	mnfPath string
+}
+
+func (opener *dirOpener) OpenArchive(archiveId uint16) (ArchiveFile, error) {
+	file, err := os.Open(getArchivePath(opener.mnfPath, archiveId))
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+type fsOpener struct {
+	fsys   fs.FS
+	name   string
+	limits reader.Limits
+}
+
+// NewFSArchiveOpener opens the .dat files next to the .mnf file name in fsys. The files that do not implement
+// io.ReaderAt, e.g. the ones of a zip file, are read into memory, up to reader.DefaultLimits.MaxSize bytes.
+func NewFSArchiveOpener(fsys fs.FS, name string) ArchiveOpener {
+	return newFSOpener(fsys, name, reader.DefaultLimits)
+}
+
+func newFSOpener(fsys fs.FS, name string, limits reader.Limits) *fsOpener {
+	return &fsOpener{
+		fsys:   fsys,
+		name:   name,
+		limits: limits,
+	}
+}
+
+func (opener *fsOpener) OpenArchive(archiveId uint16) (ArchiveFile, error) {
+	baseName := path.Base(opener.name)
+	archiveName := path.Join(path.Dir(opener.name), fmt.Sprintf("%s%04d.dat", strings.TrimSuffix(baseName, path.Ext(baseName)), archiveId))
+
+	file, err := opener.fsys.Open(archiveName)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveFile, ok := file.(ArchiveFile)
+	if ok {
+		return archiveFile, nil
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	err = opener.limits.CheckSize(uint64(max(fileInfo.Size(), 0)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", archiveName, err)
+	}
+
+	data, err := opener.limits.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", archiveName, err)
+	}
+
+	return &memoryFile{
+		Reader:   bytes.NewReader(data),
+		fileInfo: fileInfo,
+	}, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+	fileInfo fs.FileInfo
+}
+
+func (file *memoryFile) Close() error {
+	return nil
+}
+
+func (file *memoryFile) Stat() (fs.FileInfo, error) {
+	return file.fileInfo, nil
+}
@@ -0,0 +1,69 @@
+package mnf
+
+import (
+	"errors"
+	"github.com/eso-tools/eso-tools/reader"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// readerFS hides the io.ReaderAt of the files of FS, like the files of a zip file.
+type readerFS struct {
+	fs.FS
+}
+
+func (fsys readerFS) Open(name string) (fs.File, error) {
+	file, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct{ fs.File }{file}, nil
+}
+
+func TestParseFS(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMnf(t, dir, false)
+
+	mapFS := fstest.MapFS{}
+	for name, data := range readAllTestFiles(t, dir) {
+		mapFS["data/"+name] = &fstest.MapFile{Data: data}
+	}
+
+	for _, fsys := range []fs.FS{mapFS, readerFS{mapFS}} {
+		mnfData, err := ParseFS(fsys, "data/game.mnf", WithHeaders(), WithCacheDir(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, payload := range testPayloads {
+			data, err := mnfData.Read(mnfData.Index3.Block3Record(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(payload) {
+				t.Fatalf("%T: record %d: got %d bytes, want %d", fsys, i, len(data), len(payload))
+			}
+		}
+		mnfData.Close()
+	}
+
+	// the second archive is larger than MaxSize, it is not read into memory
+	limits := reader.Limits{MaxSize: 1000, MaxCount: reader.DefaultLimits.MaxCount}
+	mnfData, err := ParseFS(readerFS{mapFS}, "data/game.mnf", WithHeaders(), WithLimits(limits), WithCacheDir(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mnfData.Close()
+
+	_, err = mnfData.Read(mnfData.Index3.Block3Record(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mnfData.Read(mnfData.Index3.Block3Record(3))
+	if !errors.Is(err, reader.ErrorLimitExceeded) {
+		t.Fatalf("got %v, want %v", err, reader.ErrorLimitExceeded)
+	}
+}
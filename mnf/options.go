@@ -0,0 +1,77 @@
+package mnf
+
+import (
+	"github.com/eso-tools/eso-tools/reader"
+)
+
+type Option func(options *options)
+
+type options struct {
+	mmap        bool
+	keepHeaders bool
+	flavour     Flavour
+	limits      reader.Limits
+	cacheDir    string
+	// hashAlgorithm is nil when it is not set
+	hashAlgorithm *HashAlgorithm
+}
+
+// WithMmap maps the .dat archives into memory instead of reading them with ReadAt. It is ignored on platforms
+// without mmap.
+func WithMmap() Option {
+	return func(options *options) {
+		options.mmap = true
+	}
+}
+
+// WithHeaders keeps the entry header in the data returned by Read, Open and OpenSection. A payload that starts like a
+// header is cut otherwise.
+func WithHeaders() Option {
+	return func(options *options) {
+		options.keepHeaders = true
+	}
+}
+
+// WithFlavour sets the flavour of the manifest instead of detecting it, FlavourAuto keeps the detection.
+func WithFlavour(flavour Flavour) Option {
+	return func(options *options) {
+		options.flavour = flavour
+	}
+}
+
+// WithLimits bounds the sizes and counts Parse accepts from the .mnf file and its ZOSFT table, the uncompressed sizes
+// Read and Verify accept from the records and the archives ParseFS reads into memory, reader.DefaultLimits are used
+// otherwise.
+func WithLimits(limits reader.Limits) Option {
+	return func(options *options) {
+		options.limits = limits
+	}
+}
+
+// WithCacheDir keeps the decoded index and the ZOSFT file names of the manifests given to Parse in dir, an empty dir
+// disables the cache. The eso-tools/mnf directory of os.UserCacheDir is used otherwise.
+func WithCacheDir(dir string) Option {
+	return func(options *options) {
+		options.cacheDir = dir
+	}
+}
+
+// WithHashAlgorithm sets the algorithm Verify checks the hashes with and Replace computes them with. The algorithm of
+// the game is not identified, without it the hashes are not checked and Replace refuses the records with a hash.
+func WithHashAlgorithm(algorithm *HashAlgorithm) Option {
+	return func(options *options) {
+		options.hashAlgorithm = algorithm
+	}
+}
+
+func getOptions(opts []Option) *options {
+	options := &options{
+		limits:   reader.DefaultLimits,
+		cacheDir: getDefaultCacheDir(),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
@@ -0,0 +1,129 @@
+package mnf
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Entry is a stored file returned by Records.
+type Entry struct {
+	// Index is the row of the record in Index3
+	Index    int
+	Record2  *Block2Record
+	Record3  *Block3Record
+	FileName string
+}
+
+// Records returns the stored files in the index order with their file names, see GetFileNames. The leading records of
+// a depot are skipped. The records of missing archives and the records outside of their archive are returned without a
+// file name along with ErrorMissingArchive or ErrorNotValidRecord, the enumeration goes on after them. It stops at the
+// other errors, they are returned with a nil entry.
+func (mnfData *Mnf) Records() iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		fileNames, err := mnfData.getFileNames()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		namer := newFileNamer(fileNames)
+
+		isDepot := mnfData.IsDepot()
+		skip := isDepot
+
+		for i := 0; i < mnfData.Index3.Len(); i++ {
+			archiveIndex := mnfData.Index3.block3.archiveIndexes[i]
+
+			if isDepot && skip && archiveIndex != 0 {
+				skip = false
+			}
+
+			if skip {
+				continue
+			}
+
+			entry := &Entry{
+				Index:   i,
+				Record2: mnfData.Index3.Block2Record(i),
+				Record3: mnfData.Index3.Block3Record(i),
+			}
+
+			archive, err := mnfData.GetArchive(archiveIndex)
+			if errors.Is(err, ErrorMissingArchive) {
+				if !yield(entry, err) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !archive.IsValid(entry.Record3) {
+				err = fmt.Errorf("%w: %d bytes at offset %d are out of the archive of %d bytes", ErrorNotValidRecord, entry.Record3.CompressedSize, entry.Record3.Offset, archive.GetSize())
+				if !yield(entry, err) {
+					return
+				}
+				continue
+			}
+
+			entry.FileName = namer.getFileName(entry.Record2)
+
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// fileNamer gives the ZOSFT file name of an id to its first main record and derived names to its other records.
+type fileNamer struct {
+	fileNames map[uint32]string
+	named     map[uint32]bool
+	usedNames map[string]bool
+}
+
+func newFileNamer(fileNames map[uint32]string) *fileNamer {
+	return &fileNamer{
+		fileNames: fileNames,
+		named:     map[uint32]bool{},
+		usedNames: map[string]bool{},
+	}
+}
+
+// getFileName returns the file name of the next record in the index order. It is empty for the records without a
+// ZOSFT file name and for repeated records.
+func (namer *fileNamer) getFileName(record *Block2Record) string {
+	fileName, ok := namer.fileNames[record.Id]
+	if !ok {
+		return ""
+	}
+
+	if !namer.named[record.Id] && record.isMainRecord() {
+		namer.named[record.Id] = true
+	} else {
+		fileName = GetVariantFileName(fileName, record)
+	}
+
+	if namer.usedNames[fileName] {
+		return ""
+	}
+	namer.usedNames[fileName] = true
+
+	return fileName
+}
+
+// GetVariantFileName inserts Field2 and Flags of record in hex before the extension of fileName, "art/icon.dds"
+// becomes "art/icon.0001-0000.dds".
+func GetVariantFileName(fileName string, record *Block2Record) string {
+	ext := ""
+	i := strings.LastIndexAny(fileName, "./\\")
+	if i >= 0 && fileName[i] == '.' {
+		ext = fileName[i:]
+		fileName = fileName[:i]
+	}
+
+	return fmt.Sprintf("%s.%x-%x%s", fileName, record.Field2, record.Flags, ext)
+}
@@ -0,0 +1,47 @@
+package mnf
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRecordsErrors(t *testing.T) {
+	path := writeTestMnf(t, t.TempDir(), false)
+
+	// archive 0 keeps only the first record, archive 1 is missing
+	err := os.Truncate(getArchivePath(path, 0), int64(len(testPayloads[0])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(getArchivePath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData := mustParse(t, path)
+	defer mnfData.Close()
+
+	want := []error{nil, ErrorMissingArchive, ErrorNotValidRecord, ErrorMissingArchive}
+
+	i := 0
+	for entry, err := range mnfData.Records() {
+		if entry == nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) {
+			t.Fatalf("got %d records, want %d", i+1, len(want))
+		}
+		if entry.Index != i || entry.Record2.Id != uint32(i) {
+			t.Fatalf("record %d: got index %d and id %d", i, entry.Index, entry.Record2.Id)
+		}
+		if !errors.Is(err, want[i]) {
+			t.Fatalf("record %d: got %v, want %v", i, err, want[i])
+		}
+		i++
+	}
+
+	if i != len(want) {
+		t.Fatalf("got %d records, want %d", i, len(want))
+	}
+}
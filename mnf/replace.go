@@ -0,0 +1,342 @@
+package mnf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrorPendingJournal = errors.New("pending journal found, rollback first")
+	ErrorNotOnDisk      = errors.New("manifest is not parsed from disk")
+	ErrorNotIndexRecord = errors.New("record is not returned by the index")
+	// ErrorSkippedIndexes is returned by Replace for a manifest with SkippedIndexes, they would be lost by the rewrite
+	ErrorSkippedIndexes = errors.New("manifest has skipped indexes")
+)
+
+// journal keeps everything needed to undo a Replace that did not finish.
+type journal struct {
+	MnfBackupPath string
+	ArchivePath   string
+	ArchiveSize   int64
+	Offset        int64
+	OriginalData  []byte
+}
+
+func getJournalPath(mnfPath string) string {
+	return mnfPath + ".journal"
+}
+
+// Replace stores data as the new payload of record and rewrites the .mnf index.
+// data is given as Read returns it: the entry header of the original payload is put back in front of it unless the
+// manifest is parsed WithHeaders. The compressed payload reuses the record's slot when it fits and the slot is not
+// shared with another record, otherwise it is appended to the archive. The compression type of the record is kept,
+// ErrorNotWritableCompression is returned for the types that cannot be written. The hash is computed with
+// WithHashAlgorithm, ErrorUnknownHash is returned for a record with a hash when there is no algorithm. Until the index
+// is rewritten the original state is kept in a journal next to the .mnf file, any failure rolls it back. It is only
+// supported for manifests parsed with Parse and without SkippedIndexes, record must be returned by the index of
+// mnfData.
+func (mnfData *Mnf) Replace(record *Block3Record, data []byte) error {
+	_, ok := mnfData.archiveOpener.(*dirOpener)
+	if !ok {
+		return ErrorNotOnDisk
+	}
+
+	if record.row == 0 || record.row > mnfData.Index3.Len() {
+		return ErrorNotIndexRecord
+	}
+
+	if len(mnfData.SkippedIndexes) > 0 {
+		return ErrorSkippedIndexes
+	}
+
+	_, err := os.Stat(getJournalPath(mnfData.Path))
+	if err == nil {
+		return ErrorPendingJournal
+	}
+
+	archiveId, ok := mnfData.ArchiveIds[record.ArchiveIndex]
+	if !ok {
+		return fmt.Errorf("not valid archiveIndex: %d", record.ArchiveIndex)
+	}
+
+	algorithm := mnfData.HashAlgorithm()
+	if record.Hash != 0 && algorithm == nil {
+		return fmt.Errorf("%w: record has the hash 0x%08x", ErrorUnknownHash, record.Hash)
+	}
+
+	if !mnfData.archiveOptions.keepHeaders {
+		archive, err := mnfData.GetArchive(record.ArchiveIndex)
+		if err != nil {
+			return err
+		}
+
+		_, header, err := archive.ReadWithHeader(record)
+		if err != nil {
+			return fmt.Errorf("reading the entry header: %w", err)
+		}
+
+		if header != nil {
+			data = append(header.Bytes(), data...)
+		}
+	}
+
+	compressed, err := compress(data, record.CompressionType)
+	if err != nil {
+		return err
+	}
+
+	hash := record.Hash
+	if hash != 0 {
+		hash = algorithm.sum(compressed, data)
+	}
+
+	archivePath := getArchivePath(mnfData.Path, archiveId)
+	archiveFile, err := os.OpenFile(archivePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+
+	fi, err := archiveFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	j := &journal{
+		ArchivePath: archivePath,
+		ArchiveSize: fi.Size(),
+		Offset:      fi.Size(),
+	}
+
+	if uint64(len(compressed)) <= uint64(record.CompressedSize) && !mnfData.isSharedSlot(record) {
+		j.Offset = int64(record.Offset)
+		j.OriginalData = make([]byte, record.CompressedSize)
+		_, err = archiveFile.ReadAt(j.OriginalData, j.Offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = checkAddressable(j.Offset, len(compressed), len(data))
+	if err != nil {
+		return fmt.Errorf("archive %d: %w", record.ArchiveIndex, err)
+	}
+
+	j.MnfBackupPath, err = createBackup(mnfData.Path)
+	if err != nil {
+		return err
+	}
+
+	err = writeJournal(getJournalPath(mnfData.Path), j)
+	if err != nil {
+		os.Remove(j.MnfBackupPath)
+		return err
+	}
+
+	original := *record
+
+	err = func() error {
+		_, err := archiveFile.WriteAt(compressed, j.Offset)
+		if err != nil {
+			return err
+		}
+
+		err = archiveFile.Sync()
+		if err != nil {
+			return err
+		}
+
+		record.UncompressedSize = uint32(len(data))
+		record.CompressedSize = uint32(len(compressed))
+		record.Offset = uint32(j.Offset)
+		record.Hash = hash
+		mnfData.Index3.setBlock3Record(record.row-1, record)
+
+		return writeFileAtomic(mnfData.Path, mnfData.Write)
+	}()
+	if err != nil {
+		*record = original
+		mnfData.Index3.setBlock3Record(record.row-1, record)
+
+		rollbackErr := Rollback(mnfData.Path)
+		if rollbackErr != nil {
+			return fmt.Errorf("%s (rollback: %s)", err, rollbackErr)
+		}
+
+		return err
+	}
+
+	err = os.Remove(getJournalPath(mnfData.Path))
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(j.MnfBackupPath)
+	if err != nil {
+		return err
+	}
+
+	// the payload may have been appended past the mapped and cached size
+	slot, ok := mnfData.archives[record.ArchiveIndex]
+	if ok && slot.archive != nil {
+		return slot.archive.refresh()
+	}
+
+	return nil
+}
+
+func (mnfData *Mnf) isSharedSlot(record *Block3Record) bool {
+	block3 := &mnfData.Index3.block3
+	for i := range block3.offsets {
+		if i != record.row-1 && block3.archiveIndexes[i] == record.ArchiveIndex && block3.offsets[i] == record.Offset {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rollback undoes a Replace that did not finish. It does nothing if there is no journal for the .mnf file.
+func Rollback(mnfPath string) error {
+	journalPath := getJournalPath(mnfPath)
+
+	data, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	j := &journal{}
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		return fmt.Errorf("broken journal %q: %s", journalPath, err)
+	}
+
+	archiveFile, err := os.OpenFile(j.ArchivePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+
+	if j.OriginalData != nil {
+		_, err = archiveFile.WriteAt(j.OriginalData, j.Offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = archiveFile.Truncate(j.ArchiveSize)
+	if err != nil {
+		return err
+	}
+
+	err = archiveFile.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(mnfPath, func(w io.Writer) error {
+		f, err := os.Open(j.MnfBackupPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(journalPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(j.MnfBackupPath)
+}
+
+func writeJournal(path string, j *journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// createBackup copies the .mnf file to a new file next to it and returns its path, which is kept by the journal.
+func createBackup(mnfPath string) (string, error) {
+	var path string
+	err := func() error {
+		src, err := os.Open(mnfPath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		f, err := os.CreateTemp(filepath.Dir(mnfPath), filepath.Base(mnfPath)+".*.bak")
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		path = f.Name()
+
+		_, err = io.Copy(f, src)
+		if err != nil {
+			return err
+		}
+
+		err = f.Sync()
+		if err != nil {
+			return err
+		}
+
+		return f.Close()
+	}()
+	if err != nil {
+		if path != "" {
+			os.Remove(path)
+		}
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeFileAtomic writes a temporary file next to path, syncs it and renames it over path.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = write(f)
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
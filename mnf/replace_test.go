@@ -0,0 +1,293 @@
+package mnf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAllTestFiles(t *testing.T, dir string) map[string][]byte {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = data
+	}
+
+	return files
+}
+
+func TestReplace(t *testing.T) {
+	for _, mmap := range []bool{false, true} {
+		path := writeTestMnf(t, t.TempDir(), false)
+
+		// the last payload is made of zeros, it starts like a header
+		options := []Option{WithHeaders()}
+		if mmap {
+			options = append(options, WithMmap())
+		}
+
+		mnfData := mustParse(t, path, options...)
+
+		// the archives are opened before Replace, a grown archive is read again
+		for i := 0; i < mnfData.Index3.Len(); i++ {
+			_, err := mnfData.Read(mnfData.Index3.Block3Record(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		payloads := [][]byte{
+			[]byte("short"),
+			bytes.Repeat([]byte("a longer payload than the one it replaces "), 100),
+		}
+		for i, payload := range payloads {
+			err := mnfData.Replace(mnfData.Index3.Block3Record(i), payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := append(payloads, testPayloads[len(payloads):]...)
+		for _, mnfData := range []*Mnf{mnfData, mustParse(t, path, options...)} {
+			for i, payload := range want {
+				data, err := mnfData.Read(mnfData.Index3.Block3Record(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, payload) {
+					t.Fatalf("mmap=%t: record %d: got %q, want %q", mmap, i, data, payload)
+				}
+			}
+			mnfData.Close()
+		}
+
+		_, err := os.Stat(getJournalPath(path))
+		if !os.IsNotExist(err) {
+			t.Fatalf("the journal is not removed: %v", err)
+		}
+	}
+}
+
+func mustParse(t *testing.T, path string, options ...Option) *Mnf {
+	mnfData, err := Parse(path, append(options, WithCacheDir(""))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mnfData
+}
+
+func TestReplaceSkippedIndexes(t *testing.T) {
+	path := writeTestMnf(t, t.TempDir(), false)
+
+	err := os.WriteFile(path, patchTestMnf(t, -1, []byte{0x00, 0x07, 0x01}), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData := mustParse(t, path)
+	defer mnfData.Close()
+
+	err = mnfData.Replace(mnfData.Index3.Block3Record(0), []byte("payload"))
+	if !errors.Is(err, ErrorSkippedIndexes) {
+		t.Fatalf("got %v, want %v", err, ErrorSkippedIndexes)
+	}
+}
+
+func TestReplaceHeader(t *testing.T) {
+	header := makeTestHeader([]byte("section1"), 8, []byte("section2"))
+	payloads := [][]byte{
+		append(header, []byte("payload with a header")...),
+		[]byte("payload without a header"),
+	}
+
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+	for i, payload := range payloads {
+		for compressionType := range 2 {
+			err := writer.Add(&WriteEntry{
+				Record2: &Block2Record{
+					Id:     uint32(i),
+					Field2: []byte{0x00, 0x00},
+					Flags:  []byte{0x00, 0x00},
+				},
+				CompressionType: uint16(compressionType),
+				Data:            payload,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnfData := mustParse(t, writer.Path)
+	defer mnfData.Close()
+
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		err = mnfData.Replace(mnfData.Index3.Block3Record(i), []byte("replaced payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := mnfData.GetArchive(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < mnfData.Index3.Len(); i++ {
+		data, got, err := archive.ReadWithHeader(mnfData.Index3.Block3Record(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "replaced payload" {
+			t.Fatalf("record %d: got %q, want %q", i, data, "replaced payload")
+		}
+
+		var want []byte
+		if i/2 == 0 {
+			want = header
+		}
+		if got == nil && want != nil || got != nil && !bytes.Equal(got.Bytes(), want) {
+			t.Fatalf("record %d: got the header %v, want %q", i, got, want)
+		}
+	}
+}
+
+func TestReplaceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(record *Block3Record)
+		want   error
+	}{
+		{
+			name: "hash without algorithm",
+			modify: func(record *Block3Record) {
+				record.Hash = 0x12345678
+			},
+			want: ErrorUnknownHash,
+		},
+		{
+			name: "not writable compression",
+			modify: func(record *Block3Record) {
+				record.CompressionType = 4
+			},
+			want: ErrorNotWritableCompression,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeTestMnf(t, dir, false)
+			originals := readAllTestFiles(t, dir)
+
+			mnfData := mustParse(t, path, WithHeaders())
+			defer mnfData.Close()
+
+			record := mnfData.Index3.Block3Record(0)
+			test.modify(record)
+
+			err := mnfData.Replace(record, []byte("payload"))
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+
+			files := readAllTestFiles(t, dir)
+			if len(files) != len(originals) {
+				t.Fatalf("%d files after Replace, want %d", len(files), len(originals))
+			}
+			for name, data := range originals {
+				if !bytes.Equal(files[name], data) {
+					t.Fatalf("%s is changed", name)
+				}
+			}
+		})
+	}
+}
+
+// TestRollback leaves the state of a Replace interrupted after the archive is written and before the .mnf file is.
+func TestRollback(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestMnf(t, dir, false)
+
+	// a backup of the user is not touched
+	err := os.WriteFile(path+".bak", []byte("user backup"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originals := readAllTestFiles(t, dir)
+
+	mnfData := mustParse(t, path)
+	defer mnfData.Close()
+
+	record := mnfData.Index3.Block3Record(0)
+	archivePath := getArchivePath(path, mnfData.ArchiveIds[record.ArchiveIndex])
+	archiveSize := int64(len(originals[filepath.Base(archivePath)]))
+
+	j := &journal{
+		ArchivePath:  archivePath,
+		ArchiveSize:  archiveSize,
+		Offset:       int64(record.Offset),
+		OriginalData: testPayloads[0],
+	}
+
+	j.MnfBackupPath, err = createBackup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeJournal(getJournalPath(path), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slot is overwritten and a payload is appended
+	archiveFile, err := os.OpenFile(archivePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = archiveFile.WriteAt(bytes.Repeat([]byte{0xff}, int(record.CompressedSize)), j.Offset)
+	if err == nil {
+		_, err = archiveFile.WriteAt([]byte("appended payload"), archiveSize)
+	}
+	archiveFile.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mnfData.Replace(record, []byte("payload"))
+	if !errors.Is(err, ErrorPendingJournal) {
+		t.Fatalf("got %v, want %v", err, ErrorPendingJournal)
+	}
+
+	err = Rollback(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readAllTestFiles(t, dir)
+	if len(files) != len(originals) {
+		t.Fatalf("%d files after the rollback, want %d", len(files), len(originals))
+	}
+	for name, data := range originals {
+		if !bytes.Equal(files[name], data) {
+			t.Fatalf("%s is not restored", name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package mnf
+
+import (
+	"bytes"
+)
+
+// isMainRecord reports whether Field2 is zero. The ZOSFT file name of an id is given to its first such record, the
+// meaning of Field2 and Flags is not known.
+func (record *Block2Record) isMainRecord() bool {
+	return bytes.Equal(record.Field2, []byte{0x00, 0x00})
+}
+
+// Variant is a record of an id returned by Mnf.Variants.
+type Variant struct {
+	Index   int
+	Record2 *Block2Record
+	Record3 *Block3Record
+	// LikelyLoaded is set for the record returned by Lookup, the first one of its Field2 and Flags in the index order.
+	// It is a heuristic, the record the game loads is not known.
+	LikelyLoaded bool
+	// Skipped is set for the leading records of a depot and the records outside of their archive, they are never loaded
+	Skipped bool
+}
+
+type Variants []*Variant
+
+// Variants returns the records of id in the index order.
+func (mnfData *Mnf) Variants(id uint32) Variants {
+	index := mnfData.getLookupIndex()
+
+	variants := make(Variants, 0, len(index.ids[id]))
+	for _, i := range index.ids[id] {
+		variant := &Variant{
+			Index:   i,
+			Record2: mnfData.Index3.Block2Record(i),
+			Record3: mnfData.Index3.Block3Record(i),
+			Skipped: index.skipped[i],
+		}
+
+		if !variant.Skipped {
+			key, _ := getLookupKey(variant.Record2.Id, variant.Record2.Field2, variant.Record2.Flags)
+			variant.LikelyLoaded = index.records[key] == i
+		}
+
+		variants = append(variants, variant)
+	}
+
+	return variants
+}
+
+// GetLikelyLoaded returns the records with LikelyLoaded set.
+func (variants Variants) GetLikelyLoaded() Variants {
+	loaded := Variants{}
+	for _, variant := range variants {
+		if variant.LikelyLoaded {
+			loaded = append(loaded, variant)
+		}
+	}
+
+	return loaded
+}
+
+// GetDuplicates returns the other records with the Field2 and Flags of variant, in any archive.
+func (variants Variants) GetDuplicates(variant *Variant) Variants {
+	duplicates := Variants{}
+	for _, other := range variants {
+		if other != variant && bytes.Equal(other.Record2.Field2, variant.Record2.Field2) && bytes.Equal(other.Record2.Flags, variant.Record2.Flags) {
+			duplicates = append(duplicates, other)
+		}
+	}
+
+	return duplicates
+}
+
+// HasDuplicates reports whether a Field2 and Flags pair is stored more than once.
+func (variants Variants) HasDuplicates() bool {
+	for _, variant := range variants {
+		if len(variants.GetDuplicates(variant)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
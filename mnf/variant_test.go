@@ -0,0 +1,111 @@
+package mnf
+
+import (
+	"slices"
+	"testing"
+)
+
+func getVariantRows(variants Variants) []int {
+	rows := []int{}
+	for _, variant := range variants {
+		rows = append(rows, variant.Index)
+	}
+
+	return rows
+}
+
+func TestVariants(t *testing.T) {
+	path := writeLookupTestMnf(t)
+
+	tests := []struct {
+		name        string
+		flavour     Flavour
+		id          uint32
+		wantRows    []int
+		wantSkipped []int
+		wantLoaded  []int
+		// wantDuplicates are the rows of GetDuplicates by row
+		wantDuplicates map[int][]int
+	}{
+		{
+			name:           "variants and duplicates",
+			flavour:        FlavourOther,
+			id:             1,
+			wantRows:       []int{0, 1, 2},
+			wantSkipped:    []int{},
+			wantLoaded:     []int{0, 1},
+			wantDuplicates: map[int][]int{0: {2}, 1: {}, 2: {0}},
+		},
+		{
+			name:           "out of its archive",
+			flavour:        FlavourOther,
+			id:             2,
+			wantRows:       []int{3, 4},
+			wantSkipped:    []int{3},
+			wantLoaded:     []int{4},
+			wantDuplicates: map[int][]int{3: {4}, 4: {3}},
+		},
+		{
+			name:           "missing archive",
+			flavour:        FlavourOther,
+			id:             3,
+			wantRows:       []int{5},
+			wantSkipped:    []int{},
+			wantLoaded:     []int{5},
+			wantDuplicates: map[int][]int{5: {}},
+		},
+		{
+			name:        "unknown id",
+			flavour:     FlavourOther,
+			id:          4,
+			wantRows:    []int{},
+			wantSkipped: []int{},
+			wantLoaded:  []int{},
+		},
+		{
+			name:           "leading depot records",
+			flavour:        FlavourDepot,
+			id:             1,
+			wantRows:       []int{0, 1, 2},
+			wantSkipped:    []int{0, 1},
+			wantLoaded:     []int{2},
+			wantDuplicates: map[int][]int{0: {2}, 1: {}, 2: {0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mnfData := mustParse(t, path, WithFlavour(test.flavour))
+			defer mnfData.Close()
+
+			variants := mnfData.Variants(test.id)
+
+			rows := getVariantRows(variants)
+			if !slices.Equal(rows, test.wantRows) {
+				t.Fatalf("rows %v, want %v", rows, test.wantRows)
+			}
+
+			skipped := []int{}
+			for _, variant := range variants {
+				if variant.Skipped {
+					skipped = append(skipped, variant.Index)
+				}
+			}
+			if !slices.Equal(skipped, test.wantSkipped) {
+				t.Fatalf("skipped %v, want %v", skipped, test.wantSkipped)
+			}
+
+			loaded := getVariantRows(variants.GetLikelyLoaded())
+			if !slices.Equal(loaded, test.wantLoaded) {
+				t.Fatalf("likely loaded %v, want %v", loaded, test.wantLoaded)
+			}
+
+			for _, variant := range variants {
+				duplicates := getVariantRows(variants.GetDuplicates(variant))
+				if !slices.Equal(duplicates, test.wantDuplicates[variant.Index]) {
+					t.Fatalf("duplicates of %d: %v, want %v", variant.Index, duplicates, test.wantDuplicates[variant.Index])
+				}
+			}
+		})
+	}
+}
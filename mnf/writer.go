@@ -0,0 +1,466 @@
+package mnf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+var (
+	ErrorWriterClosed = errors.New("writer is closed")
+	// ErrorNotWritableCompression is returned for the compression types that have a decompressor but no compressor
+	ErrorNotWritableCompression = errors.New("compression type is not writable")
+)
+
+// WriteEntry is a single file stored by Writer.
+type WriteEntry struct {
+	Record2         *Block2Record
+	ArchiveIndex    uint16
+	CompressionType uint16
+	// Hash is computed with Writer.HashAlgorithm when it is zero and the writer has one
+	Hash uint32
+	Data []byte
+
+	// Raw means that Data is already encoded with CompressionType (as returned by Mnf.ReadRaw)
+	// and UncompressedSize is stored as is.
+	Raw              bool
+	UncompressedSize uint32
+}
+
+// Writer builds a new .mnf file and its numbered .dat archives.
+// Payloads are appended to the archives by Add, the index is written by Close. Close also creates an empty .dat file
+// for every archive index below the highest one used by Add or set in ArchiveIds, the header counts them all.
+type Writer struct {
+	Path       string
+	Version    uint16
+	Field5     uint32
+	ArchiveIds map[uint16]uint16
+	Index0     *Index0
+
+	Index3Field1 []byte
+	// Block1Records are written as is when set, otherwise a sequential table with one record per entry is written.
+	Block1Records []*Block1Record
+	// HashAlgorithm computes the hash of the entries without one, e.g. Mnf.HashAlgorithm of the copied manifest
+	HashAlgorithm *HashAlgorithm
+
+	archives map[uint16]*os.File
+	offsets  map[uint16]int64
+	index3   *Index3
+	closed   bool
+}
+
+func NewWriter(path string) *Writer {
+	return &Writer{
+		Path:         path,
+		Version:      3,
+		ArchiveIds:   map[uint16]uint16{},
+		Index3Field1: make([]byte, 4),
+		archives:     map[uint16]*os.File{},
+		offsets:      map[uint16]int64{},
+		index3:       &Index3{},
+	}
+}
+
+func (writer *Writer) Add(entry *WriteEntry) error {
+	if writer.closed {
+		return ErrorWriterClosed
+	}
+
+	if entry.Record2 == nil {
+		return errors.New("entry without Record2")
+	}
+
+	// nothing is written for an entry that cannot be indexed
+	err := checkBlock2Record(entry.Record2)
+	if err != nil {
+		return err
+	}
+
+	data := entry.Data
+	uncompressedSize := int(entry.UncompressedSize)
+	if !entry.Raw {
+		var err error
+		data, err = compress(entry.Data, entry.CompressionType)
+		if err != nil {
+			return err
+		}
+		uncompressedSize = len(entry.Data)
+	}
+
+	hash := entry.Hash
+	if hash == 0 && writer.HashAlgorithm != nil {
+		var err error
+		hash, err = writer.HashAlgorithm.sumEntry(entry, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	archive, err := writer.getArchive(entry.ArchiveIndex)
+	if err != nil {
+		return err
+	}
+
+	offset := writer.offsets[entry.ArchiveIndex]
+	err = checkAddressable(offset, len(data), uncompressedSize)
+	if err != nil {
+		return fmt.Errorf("archive %d: %w", entry.ArchiveIndex, err)
+	}
+
+	// the bytes of a failed write are overwritten by the next entry or truncated by Close
+	_, err = archive.WriteAt(data, offset)
+	if err != nil {
+		return err
+	}
+	writer.offsets[entry.ArchiveIndex] = offset + int64(len(data))
+
+	return writer.index3.addRecord(entry.Record2, &Block3Record{
+		UncompressedSize: uint32(uncompressedSize),
+		CompressedSize:   uint32(len(data)),
+		Hash:             hash,
+		Offset:           uint32(offset),
+		ArchiveIndex:     entry.ArchiveIndex,
+		CompressionType:  entry.CompressionType,
+	})
+}
+
+// sumEntry returns the hash of entry stored as compressed, the data of raw entries is decompressed for the uncompressed
+// algorithms.
+func (algorithm *HashAlgorithm) sumEntry(entry *WriteEntry, compressed []byte) (uint32, error) {
+	if algorithm.Compressed || !entry.Raw {
+		return algorithm.sum(compressed, entry.Data), nil
+	}
+
+	decompressor, ok := GetDecompressor(entry.CompressionType)
+	if !ok {
+		return 0, fmt.Errorf("unsupported compressionType: %d", entry.CompressionType)
+	}
+
+	data, err := decompressor.Decompress(compressed, entry.UncompressedSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", decompressor.Name, err)
+	}
+
+	return algorithm.Sum(data), nil
+}
+
+// Close closes every archive and writes the .mnf file, the errors of the archives are joined.
+func (writer *Writer) Close() error {
+	if writer.closed {
+		return ErrorWriterClosed
+	}
+	writer.closed = true
+
+	var archiveCount uint16
+	for archiveIndex := range writer.ArchiveIds {
+		archiveCount = max(archiveCount, archiveIndex+1)
+	}
+	for archiveIndex := range writer.archives {
+		archiveCount = max(archiveCount, archiveIndex+1)
+	}
+
+	var errs []error
+
+	// every archive listed in the header must exist to be parsed back
+	for archiveIndex := uint16(0); archiveIndex < archiveCount; archiveIndex++ {
+		_, err := writer.getArchive(archiveIndex)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+
+	for archiveIndex, archive := range writer.archives {
+		errs = append(errs, archive.Truncate(writer.offsets[archiveIndex]))
+		errs = append(errs, archive.Close())
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return err
+	}
+
+	index3Data := writer.index3
+	index3Data.Field1 = writer.Index3Field1
+	if writer.Block1Records == nil {
+		for i := 0; i < index3Data.Len(); i++ {
+			index3Data.addBlock1Record(&Block1Record{
+				Index: uint32(i),
+			})
+		}
+	}
+	for _, record := range writer.Block1Records {
+		index3Data.addBlock1Record(record)
+	}
+	index3Data.Count1 = uint32(index3Data.Block1Len())
+	index3Data.Count2 = uint32(index3Data.Len())
+	index3Data.Count3 = uint32(index3Data.Len())
+
+	mnfData := &Mnf{
+		Path:         writer.Path,
+		Signature:    signature,
+		Version:      writer.Version,
+		ArchiveCount: archiveCount,
+		ArchiveIds:   writer.ArchiveIds,
+		Field5:       writer.Field5,
+		Index0:       writer.Index0,
+		Index3:       index3Data,
+	}
+
+	f, err := os.Create(writer.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = mnfData.Write(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func (writer *Writer) getArchive(archiveIndex uint16) (*os.File, error) {
+	archive, ok := writer.archives[archiveIndex]
+	if ok {
+		return archive, nil
+	}
+
+	archiveId, ok := writer.ArchiveIds[archiveIndex]
+	if !ok {
+		archiveId = archiveIndex
+		writer.ArchiveIds[archiveIndex] = archiveId
+	}
+
+	archive, err := os.Create(getArchivePath(writer.Path, archiveId))
+	if err != nil {
+		return nil, err
+	}
+	writer.archives[archiveIndex] = archive
+
+	return archive, nil
+}
+
+// Write serializes the header and the indexes to w.
+// Block sizes and DataSize are recomputed, counts are written as they are.
+func (mnfData *Mnf) Write(w io.Writer) error {
+	var err error
+
+	indexBuf := bytes.NewBuffer(nil)
+
+	if mnfData.Index0 != nil {
+		err = writeIndex0(indexBuf, mnfData.Index0)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mnfData.Index3 != nil {
+		err = writeIndex3(indexBuf, mnfData.Index3)
+		if err != nil {
+			return err
+		}
+	}
+
+	if uint64(indexBuf.Len()) > math.MaxUint32 {
+		return errors.New("index exceeds 4 GiB")
+	}
+	mnfData.DataSize = uint32(indexBuf.Len())
+
+	buf := bufio.NewWriterSize(w, 1024*1024)
+	w = buf
+
+	_, err = w.Write([]byte(signature))
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, mnfData.Version)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, mnfData.ArchiveCount)
+	if err != nil {
+		return err
+	}
+
+	for i := uint16(0); i < mnfData.ArchiveCount; i++ {
+		archiveId, ok := mnfData.ArchiveIds[i]
+		if !ok {
+			return fmt.Errorf("no archive id for archiveIndex: %d", i)
+		}
+
+		err = binary.Write(w, binary.LittleEndian, archiveId)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = binary.Write(w, binary.LittleEndian, mnfData.Field5)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, mnfData.DataSize)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(indexBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
+}
+
+func writeIndex0(w io.Writer, index0Data *Index0) error {
+	var err error
+
+	if len(index0Data.Field1) != 2 {
+		return errors.New("not valid Index0: Field1 must be 2 bytes long")
+	}
+
+	index0Data.Block1Size = uint32(len(index0Data.Block1Data))
+	index0Data.Block2Size = uint32(len(index0Data.Block2Data))
+
+	err = binary.Write(w, binary.BigEndian, uint16(0))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(index0Data.Field1)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.BigEndian, index0Data.Block1Size)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(index0Data.Block1Data)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.BigEndian, index0Data.Block2Size)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(index0Data.Block2Data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeIndex3(w io.Writer, index3Data *Index3) error {
+	var err error
+
+	if len(index3Data.Field1) != 4 {
+		return errors.New("not valid Index3: Field1 must be 4 bytes long")
+	}
+
+	block1Data := index3Data.block1.encode()
+	block2Data := index3Data.block2.encode()
+	block3Data := index3Data.block3.encode()
+
+	err = binary.Write(w, binary.BigEndian, uint16(3))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(index3Data.Field1)
+	if err != nil {
+		return err
+	}
+
+	for _, count := range []uint32{index3Data.Count1, index3Data.Count2, index3Data.Count3} {
+		err = binary.Write(w, binary.BigEndian, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	index3Data.UncompressedBlock1Size, index3Data.CompressedBlock1Size, err = writeBlock(w, block1Data)
+	if err != nil {
+		return err
+	}
+
+	index3Data.UncompressedBlock2Size, index3Data.CompressedBlock2Size, err = writeBlock(w, block2Data)
+	if err != nil {
+		return err
+	}
+
+	index3Data.UncompressedBlock3Size, index3Data.CompressedBlock3Size, err = writeBlock(w, block3Data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeBlock writes a zlib compressed block prefixed with its uncompressed and compressed sizes.
+func writeBlock(w io.Writer, data []byte) (uint32, uint32, error) {
+	compressed, err := compress(data, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	uncompressedSize := uint32(len(data))
+	compressedSize := uint32(len(compressed))
+
+	err = binary.Write(w, binary.BigEndian, uncompressedSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = binary.Write(w, binary.BigEndian, compressedSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = w.Write(compressed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uncompressedSize, compressedSize, nil
+}
+
+func compress(data []byte, compressionType uint16) ([]byte, error) {
+	switch compressionType {
+	case 0:
+		return data, nil
+
+	case 1:
+		buf := bytes.NewBuffer(nil)
+		zlibWriter := zlib.NewWriter(buf)
+
+		_, err := zlibWriter.Write(data)
+		if err != nil {
+			return nil, err
+		}
+
+		err = zlibWriter.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrorNotWritableCompression, compressionType)
+	}
+}
@@ -0,0 +1,145 @@
+package mnf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	for _, withIndex0 := range []bool{false, true} {
+		path := writeTestMnf(t, t.TempDir(), withIndex0)
+
+		original, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := original
+		for i := 0; i < 2; i++ {
+			mnfData, err := ParseReader(bytes.NewReader(data), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := bytes.NewBuffer(nil)
+			err = mnfData.Write(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(buf.Bytes(), original) {
+				t.Fatalf("withIndex0=%t: write %d: got %d bytes that differ from the %d bytes parsed", withIndex0, i+1, buf.Len(), len(original))
+			}
+			data = buf.Bytes()
+		}
+	}
+}
+
+func TestWriterCloseArchives(t *testing.T) {
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+
+	for archiveIndex := uint16(0); archiveIndex < 3; archiveIndex++ {
+		_, err := writer.getArchive(archiveIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first archive fails to close, the others must be closed anyway
+	writer.archives[0].Close()
+
+	err := writer.Close()
+	if !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("got %v, want %v", err, os.ErrClosed)
+	}
+
+	for archiveIndex, archive := range writer.archives {
+		err = archive.Close()
+		if !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("archive %d is not closed: %v", archiveIndex, err)
+		}
+	}
+}
+
+func TestWriterAdd(t *testing.T) {
+	writer := NewWriter(filepath.Join(t.TempDir(), "game.mnf"))
+
+	entries := []struct {
+		entry   *WriteEntry
+		wantErr bool
+	}{
+		{
+			entry: &WriteEntry{
+				Record2:      &Block2Record{Id: 1, Field2: []byte{0x00, 0x00}, Flags: []byte{0x00, 0x00}},
+				ArchiveIndex: 2,
+				Data:         []byte("first payload"),
+			},
+		},
+		{
+			entry: &WriteEntry{
+				Record2:      &Block2Record{Id: 2, Field2: []byte{0x00}, Flags: []byte{0x00, 0x00}},
+				ArchiveIndex: 2,
+				Data:         []byte("payload of a record that cannot be indexed"),
+			},
+			wantErr: true,
+		},
+		{
+			entry: &WriteEntry{
+				Record2:         &Block2Record{Id: 3, Field2: []byte{0x00, 0x00}, Flags: []byte{0x00, 0x00}},
+				ArchiveIndex:    2,
+				CompressionType: 4,
+				Data:            []byte("payload of a compression that cannot be written"),
+			},
+			wantErr: true,
+		},
+		{
+			entry: &WriteEntry{
+				Record2:      &Block2Record{Id: 4, Field2: []byte{0x00, 0x00}, Flags: []byte{0x00, 0x00}},
+				ArchiveIndex: 2,
+				Data:         []byte("second payload"),
+			},
+		},
+	}
+
+	for i, test := range entries {
+		err := writer.Add(test.entry)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("entry %d: got %v, want an error: %t", i, err, test.wantErr)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the archives below the last one are created empty
+	for archiveIndex, want := range []int{0, 0, len("first payload") + len("second payload")} {
+		info, err := os.Stat(getArchivePath(writer.Path, uint16(archiveIndex)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(want) {
+			t.Fatalf("archive %d: %d bytes, want %d", archiveIndex, info.Size(), want)
+		}
+	}
+
+	mnfData := mustParse(t, writer.Path)
+	defer mnfData.Close()
+
+	if mnfData.ArchiveCount != 3 || mnfData.Index3.Len() != 2 {
+		t.Fatalf("%d archives and %d records, want 3 and 2", mnfData.ArchiveCount, mnfData.Index3.Len())
+	}
+	for i, want := range []string{"first payload", "second payload"} {
+		data, err := mnfData.Read(mnfData.Index3.Block3Record(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("record %d: got %q, want %q", i, data, want)
+		}
+	}
+}
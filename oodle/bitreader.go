@@ -0,0 +1,293 @@
+package oodle
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// bitReader reads bits MSB first. A backward reader walks from p down to pEnd.
+type bitReader struct {
+	data     []byte
+	p        int
+	pEnd     int
+	bits     uint32
+	bitpos   int
+	backward bool
+}
+
+func newBitReader(data []byte) *bitReader {
+	br := &bitReader{
+		data:   data,
+		p:      0,
+		pEnd:   len(data),
+		bitpos: 24,
+	}
+	br.refill()
+
+	return br
+}
+
+func newBackwardBitReader(data []byte) *bitReader {
+	br := &bitReader{
+		data:     data,
+		p:        len(data),
+		pEnd:     0,
+		bitpos:   24,
+		backward: true,
+	}
+	br.refill()
+
+	return br
+}
+
+func (br *bitReader) refill() {
+	if br.backward {
+		for br.bitpos > 0 {
+			br.p--
+			if br.p >= br.pEnd && br.p < len(br.data) {
+				br.bits |= uint32(br.data[br.p]) << br.bitpos
+			}
+			br.bitpos -= 8
+		}
+		return
+	}
+
+	for br.bitpos > 0 {
+		if br.p < br.pEnd {
+			br.bits |= uint32(br.data[br.p]) << br.bitpos
+		}
+		br.bitpos -= 8
+		br.p++
+	}
+}
+
+func (br *bitReader) readBitNoRefill() uint32 {
+	r := br.bits >> 31
+	br.bits <<= 1
+	br.bitpos++
+
+	return r
+}
+
+func (br *bitReader) readBitsNoRefill(n int) uint32 {
+	r := br.bits >> (32 - n)
+	br.bits <<= n
+	br.bitpos += n
+
+	return r
+}
+
+func (br *bitReader) readBitsNoRefillZero(n int) uint32 {
+	r := br.bits >> 1 >> (31 - n)
+	br.bits <<= n
+	br.bitpos += n
+
+	return r
+}
+
+func (br *bitReader) readMoreThan24Bits(n int) uint32 {
+	var rv uint32
+	if n <= 24 {
+		rv = br.readBitsNoRefillZero(n)
+	} else {
+		rv = br.readBitsNoRefill(24) << (n - 24)
+		br.refill()
+		rv += br.readBitsNoRefill(n - 24)
+	}
+	br.refill()
+
+	return rv
+}
+
+func (br *bitReader) readDistance(v uint32) uint32 {
+	var rv uint32
+	if v < 0xf0 {
+		n := int(v>>4) + 4
+		w := bits.RotateLeft32(br.bits|1, n)
+		br.bitpos += n
+		m := uint32(2)<<n - 1
+		br.bits = w &^ m
+		rv = ((w & m) << 4) + (v & 0xf) - 248
+	} else {
+		n := int(v) - 0xf0 + 4
+		w := bits.RotateLeft32(br.bits|1, n)
+		br.bitpos += n
+		m := uint32(2)<<n - 1
+		br.bits = w &^ m
+		rv = 8322816 + ((w & m) << 12)
+		br.refill()
+		rv += br.bits >> 20
+		br.bitpos += 12
+		br.bits <<= 12
+	}
+	br.refill()
+
+	return rv
+}
+
+func (br *bitReader) readLength() (uint32, bool) {
+	n := bits.LeadingZeros32(br.bits)
+	if n > 12 {
+		return 0, false
+	}
+	br.bitpos += n
+	br.bits <<= n
+	br.refill()
+
+	n += 7
+	br.bitpos += n
+	rv := (br.bits >> (32 - n)) - 64
+	br.bits <<= n
+	br.refill()
+
+	return rv, true
+}
+
+func (br *bitReader) readFluff(numSymbols int) int {
+	if numSymbols == 256 {
+		return 0
+	}
+
+	x := 257 - numSymbols
+	if x > numSymbols {
+		x = numSymbols
+	}
+	x *= 2
+
+	y := bits.Len32(uint32(x - 1))
+	v := br.bits >> (32 - y)
+	z := uint32(1)<<y - uint32(x)
+
+	if v>>1 >= z {
+		br.bits <<= y
+		br.bitpos += y
+		return int(v - z)
+	}
+
+	br.bits <<= y - 1
+	br.bitpos += y - 1
+
+	return int(v >> 1)
+}
+
+// bytePos returns the position of the first byte that was not touched by the reader.
+func (br *bitReader) bytePos() int {
+	return br.p - (24-br.bitpos)>>3
+}
+
+// bitReader2 is a plain position in a byte stream, used by the Golomb-Rice decoders.
+type bitReader2 struct {
+	data   []byte
+	p      int
+	bitpos int
+}
+
+func (br *bitReader) toBitReader2() *bitReader2 {
+	return &bitReader2{
+		data:   br.data[:br.pEnd],
+		p:      br.p - (24-br.bitpos+7)>>3,
+		bitpos: (br.bitpos - 24) & 7,
+	}
+}
+
+func (br *bitReader) resetFrom(br2 *bitReader2) {
+	br.bitpos = 24
+	br.p = br2.p
+	br.bits = 0
+	br.refill()
+	br.bits <<= br2.bitpos
+	br.bitpos += br2.bitpos
+}
+
+func (br2 *bitReader2) readBit() (byte, bool) {
+	if br2.p >= len(br2.data) {
+		return 0, false
+	}
+
+	bit := (br2.data[br2.p] >> (7 - br2.bitpos)) & 1
+	br2.bitpos++
+	if br2.bitpos == 8 {
+		br2.bitpos = 0
+		br2.p++
+	}
+
+	return bit, true
+}
+
+// decodeGolombRiceLengths reads unary values, the number of zero bits before each one bit.
+func decodeGolombRiceLengths(dst []byte, br2 *bitReader2) bool {
+	if br2.p >= len(br2.data) {
+		return false
+	}
+
+	count := 0
+	for i := 0; i < len(dst); {
+		bit, ok := br2.readBit()
+		if !ok {
+			return false
+		}
+		if bit == 0 {
+			count++
+			continue
+		}
+		dst[i] = byte(count)
+		count = 0
+		i++
+	}
+
+	return true
+}
+
+// decodeGolombRiceBits appends bitCount low bits to every value in dst.
+func decodeGolombRiceBits(dst []byte, bitCount int, br2 *bitReader2) bool {
+	if bitCount == 0 {
+		return true
+	}
+
+	bitsRequired := br2.bitpos + bitCount*len(dst)
+	if (bitsRequired+7)>>3 > len(br2.data)-br2.p {
+		return false
+	}
+
+	for i := range dst {
+		v := dst[i]
+		for j := 0; j < bitCount; j++ {
+			bit, _ := br2.readBit()
+			v = v<<1 | bit
+		}
+		dst[i] = v
+	}
+
+	return true
+}
+
+// readLE32 and readBE32 treat the bytes outside of data as zeroes.
+func readLE32(data []byte, pos int) uint32 {
+	if pos >= 0 && pos+4 <= len(data) {
+		return binary.LittleEndian.Uint32(data[pos:])
+	}
+
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if pos+i >= 0 && pos+i < len(data) {
+			v |= uint32(data[pos+i]) << (8 * i)
+		}
+	}
+
+	return v
+}
+
+func readBE32(data []byte, pos int) uint32 {
+	if pos >= 0 && pos+4 <= len(data) {
+		return binary.BigEndian.Uint32(data[pos:])
+	}
+
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if pos+i >= 0 && pos+i < len(data) {
+			v |= uint32(data[pos+i]) << (24 - 8*i)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package oodle
+
+func decompressDll(data []byte, uncompressedSize int64) ([]byte, bool, error) {
+	return nil, false, nil
+}
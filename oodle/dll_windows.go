@@ -0,0 +1,20 @@
+//go:build windows
+
+package oodle
+
+import (
+	dll "github.com/new-world-tools/go-oodle"
+	"sync"
+)
+
+var isDllExist = sync.OnceValue(dll.IsDllExist)
+
+func decompressDll(data []byte, uncompressedSize int64) ([]byte, bool, error) {
+	if !isDllExist() {
+		return nil, false, nil
+	}
+
+	output, err := dll.Decompress(data, uncompressedSize)
+
+	return output, true, err
+}
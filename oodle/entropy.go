@@ -0,0 +1,1256 @@
+package oodle
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"slices"
+)
+
+const maxBlockSize = 0x40000
+
+// parseBlockHeader reads the header of an entropy coded block. Stored blocks (type 0) have equal sizes.
+func parseBlockHeader(src []byte) (chunkType int, srcSize int, dstSize int, pos int, err error) {
+	if len(src) < 2 {
+		return 0, 0, 0, 0, ErrorCorruptedData
+	}
+
+	chunkType = int(src[0]>>4) & 7
+	if chunkType == 0 {
+		if src[0] >= 0x80 {
+			srcSize = (int(src[0])<<8 | int(src[1])) & 0xfff
+			pos = 2
+		} else {
+			if len(src) < 3 {
+				return 0, 0, 0, 0, ErrorCorruptedData
+			}
+			srcSize = int(src[0])<<16 | int(src[1])<<8 | int(src[2])
+			if srcSize&^0x3ffff != 0 {
+				return 0, 0, 0, 0, ErrorCorruptedData
+			}
+			pos = 3
+		}
+
+		return chunkType, srcSize, srcSize, pos, nil
+	}
+
+	if src[0] >= 0x80 {
+		if len(src) < 3 {
+			return 0, 0, 0, 0, ErrorCorruptedData
+		}
+		v := int(src[0])<<16 | int(src[1])<<8 | int(src[2])
+		srcSize = v & 0x3ff
+		dstSize = srcSize + (v>>10)&0x3ff + 1
+		pos = 3
+	} else {
+		if len(src) < 5 {
+			return 0, 0, 0, 0, ErrorCorruptedData
+		}
+		v := int(src[1])<<24 | int(src[2])<<16 | int(src[3])<<8 | int(src[4])
+		srcSize = v & 0x3ffff
+		dstSize = ((v>>18)|int(src[0])<<14)&0x3ffff + 1
+		if srcSize >= dstSize {
+			return 0, 0, 0, 0, ErrorCorruptedData
+		}
+		pos = 5
+	}
+
+	return chunkType, srcSize, dstSize, pos, nil
+}
+
+// getBlockSize returns the decoded size of the block at the start of src without decoding it.
+func getBlockSize(src []byte, capacity int) (int, error) {
+	chunkType, srcSize, dstSize, pos, err := parseBlockHeader(src)
+	if err != nil {
+		return 0, err
+	}
+
+	if chunkType >= 6 || len(src)-pos < srcSize || dstSize > capacity {
+		return 0, ErrorCorruptedData
+	}
+
+	return dstSize, nil
+}
+
+// decodeBytes decodes the entropy coded block at the start of src and returns the decoded bytes and the number of
+// bytes read from src. The block is decoded into dst, or a new buffer if dst is nil. Stored blocks are returned as
+// a part of src unless forceCopy is set.
+func decodeBytes(src []byte, dst []byte, outputSize int, forceCopy bool) ([]byte, int, error) {
+	chunkType, srcSize, dstSize, pos, err := parseBlockHeader(src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(src)-pos < srcSize || dstSize > outputSize {
+		return nil, 0, ErrorCorruptedData
+	}
+
+	body := src[pos : pos+srcSize]
+	if chunkType == 0 && !forceCopy {
+		return body, pos + srcSize, nil
+	}
+
+	if dst == nil {
+		dst = make([]byte, dstSize)
+	}
+	if len(dst) < dstSize {
+		return nil, 0, ErrorCorruptedData
+	}
+	dst = dst[:dstSize]
+
+	var used int
+	switch chunkType {
+	case 0:
+		used = copy(dst, body)
+
+	case 1:
+		used, err = decodeTans(body, dst)
+
+	case 2, 4:
+		used, err = decodeHuffman(body, dst, chunkType>>1)
+
+	case 3:
+		used, err = decodeRLE(body, dst)
+
+	case 5:
+		used, err = decodeRecursive(body, dst)
+
+	default:
+		return nil, 0, ErrorCorruptedData
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if used != srcSize {
+		return nil, 0, ErrorCorruptedData
+	}
+
+	return dst, pos + srcSize, nil
+}
+
+var codePrefixOrg = [12]uint32{0x0, 0x0, 0x2, 0x6, 0xe, 0x1e, 0x3e, 0x7e, 0xfe, 0x1fe, 0x2fe, 0x3fe}
+
+type huffLut struct {
+	bits2len [2048]byte
+	bits2sym [2048]byte
+}
+
+// decodeHuffman decodes a block of 3 (typ 1) or 6 (typ 2) interleaved huffman streams.
+func decodeHuffman(src []byte, dst []byte, typ int) (int, error) {
+	br := newBitReader(src)
+
+	codePrefix := codePrefixOrg
+	var syms [1280]byte
+	var numSyms int
+	var err error
+	if br.readBitNoRefill() == 0 {
+		numSyms, err = readCodeLengthsOld(br, syms[:], &codePrefix)
+	} else if br.readBitNoRefill() == 0 {
+		numSyms, err = readCodeLengthsNew(br, syms[:], &codePrefix)
+	} else {
+		return 0, ErrorCorruptedData
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if numSyms < 1 {
+		return 0, ErrorCorruptedData
+	}
+
+	pos := br.bytePos()
+
+	if numSyms == 1 {
+		for i := range dst {
+			dst[i] = syms[0]
+		}
+		return len(src), nil
+	}
+
+	lut, err := makeHuffLut(&codePrefix, syms[:])
+	if err != nil {
+		return 0, err
+	}
+
+	if typ == 1 {
+		if pos+3 > len(src) {
+			return 0, ErrorCorruptedData
+		}
+		splitMid := int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+
+		err = decodeHuffmanStreams(dst, src, pos, pos+splitMid, len(src), lut)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(src), nil
+	}
+
+	if pos+6 > len(src) {
+		return 0, ErrorCorruptedData
+	}
+
+	halfOutputSize := (len(dst) + 1) >> 1
+	splitMid := int(binary.LittleEndian.Uint32(src[pos:]) & 0xffffff)
+	pos += 3
+	if splitMid > len(src)-pos {
+		return 0, ErrorCorruptedData
+	}
+	mid := pos + splitMid
+	splitLeft := int(binary.LittleEndian.Uint16(src[pos:]))
+	pos += 2
+	if mid-pos < splitLeft+2 || len(src)-mid < 3 {
+		return 0, ErrorCorruptedData
+	}
+	splitRight := int(binary.LittleEndian.Uint16(src[mid:]))
+	if len(src)-(mid+2) < splitRight+2 {
+		return 0, ErrorCorruptedData
+	}
+
+	err = decodeHuffmanStreams(dst[:halfOutputSize], src, pos, pos+splitLeft, mid, lut)
+	if err != nil {
+		return 0, err
+	}
+
+	err = decodeHuffmanStreams(dst[halfOutputSize:], src, mid+2, mid+2+splitRight, len(src), lut)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(src), nil
+}
+
+// decodeHuffmanStreams decodes three streams into dst in turn: forwards from srcPos, backwards from srcEnd and
+// forwards from srcMid. The streams have to meet exactly.
+func decodeHuffmanStreams(dst []byte, src []byte, srcPos int, srcMid int, srcEnd int, lut *huffLut) error {
+	if srcPos < 0 || srcPos > srcMid || srcMid > srcEnd || srcEnd > len(src) {
+		return ErrorCorruptedData
+	}
+
+	srcMidOrg := srcMid
+	var srcBits, midBits, endBits uint32
+	var srcBitpos, midBitpos, endBitpos int
+
+	for i := 0; i < len(dst); {
+		if srcMid-srcPos <= 1 {
+			if srcMid-srcPos == 1 {
+				srcBits |= uint32(src[srcPos]) << srcBitpos
+			}
+		} else {
+			srcBits |= uint32(binary.LittleEndian.Uint16(src[srcPos:])) << srcBitpos
+		}
+		k := srcBits & 0x7ff
+		n := int(lut.bits2len[k])
+		srcBitpos -= n
+		srcBits >>= n
+		dst[i] = lut.bits2sym[k]
+		i++
+		srcPos += (7 - srcBitpos) >> 3
+		srcBitpos &= 7
+
+		if i < len(dst) {
+			if srcEnd-srcMid <= 1 {
+				if srcEnd-srcMid == 1 {
+					endBits |= uint32(src[srcMid]) << endBitpos
+					midBits |= uint32(src[srcMid]) << midBitpos
+				}
+			} else {
+				endBits |= (uint32(src[srcEnd-2])<<8 | uint32(src[srcEnd-1])) << endBitpos
+				midBits |= uint32(binary.LittleEndian.Uint16(src[srcMid:])) << midBitpos
+			}
+
+			k = endBits & 0x7ff
+			n = int(lut.bits2len[k])
+			dst[i] = lut.bits2sym[k]
+			i++
+			endBitpos -= n
+			endBits >>= n
+			srcEnd -= (7 - endBitpos) >> 3
+			endBitpos &= 7
+
+			if i < len(dst) {
+				k = midBits & 0x7ff
+				n = int(lut.bits2len[k])
+				dst[i] = lut.bits2sym[k]
+				i++
+				midBitpos -= n
+				midBits >>= n
+				srcMid += (7 - midBitpos) >> 3
+				midBitpos &= 7
+			}
+		}
+
+		if srcPos > srcMid || srcMid > srcEnd {
+			return ErrorCorruptedData
+		}
+	}
+
+	if srcPos != srcMidOrg || srcEnd != srcMid {
+		return ErrorCorruptedData
+	}
+
+	return nil
+}
+
+func readCodeLengthsOld(br *bitReader, syms []byte, codePrefix *[12]uint32) (int, error) {
+	if br.readBitNoRefill() == 0 {
+		// sparse symbols
+		numSymbols := int(br.readBitsNoRefill(8))
+		if numSymbols == 0 {
+			return 0, ErrorCorruptedData
+		}
+
+		if numSymbols == 1 {
+			syms[0] = byte(br.readBitsNoRefill(8))
+			return numSymbols, nil
+		}
+
+		codeLenBits := int(br.readBitsNoRefill(3))
+		if codeLenBits > 4 {
+			return 0, ErrorCorruptedData
+		}
+		for i := 0; i < numSymbols; i++ {
+			br.refill()
+			sym := byte(br.readBitsNoRefill(8))
+			codeLen := br.readBitsNoRefillZero(codeLenBits) + 1
+			if codeLen > 11 {
+				return 0, ErrorCorruptedData
+			}
+			syms[codePrefix[codeLen]] = sym
+			codePrefix[codeLen]++
+		}
+
+		return numSymbols, nil
+	}
+
+	sym := 0
+	numSymbols := 0
+	avgBitsX4 := 32
+	forcedBits := int(br.readBitsNoRefill(2))
+
+	threshold := uint32(1) << (31 - (20 >> forcedBits))
+	skipInitialZeros := br.readBitNoRefill() != 0
+	br.refill()
+	for sym != 256 {
+		if !skipInitialZeros {
+			if br.bits&0xff000000 == 0 {
+				return 0, ErrorCorruptedData
+			}
+			sym += int(br.readBitsNoRefill(2*(bits.LeadingZeros32(br.bits)+1))) - 2 + 1
+			if sym >= 256 {
+				break
+			}
+		}
+		skipInitialZeros = false
+		br.refill()
+
+		if br.bits&0xff000000 == 0 {
+			return 0, ErrorCorruptedData
+		}
+		n := int(br.readBitsNoRefill(2*(bits.LeadingZeros32(br.bits)+1))) - 2 + 1
+		if sym+n > 256 {
+			return 0, ErrorCorruptedData
+		}
+		br.refill()
+		numSymbols += n
+
+		for ; n > 0; n-- {
+			if br.bits < threshold {
+				return 0, ErrorCorruptedData
+			}
+
+			lz := bits.LeadingZeros32(br.bits)
+			v := int(br.readBitsNoRefill(lz+forcedBits+1)) + (lz-1)<<forcedBits
+			codeLen := (-(v & 1) ^ (v >> 1)) + (avgBitsX4+2)>>2
+			if codeLen < 1 || codeLen > 11 {
+				return 0, ErrorCorruptedData
+			}
+			avgBitsX4 = codeLen + (3*avgBitsX4+2)>>2
+			br.refill()
+			syms[codePrefix[codeLen]] = byte(sym)
+			codePrefix[codeLen]++
+			sym++
+		}
+	}
+
+	if sym != 256 || numSymbols < 2 {
+		return 0, ErrorCorruptedData
+	}
+
+	return numSymbols, nil
+}
+
+func readCodeLengthsNew(br *bitReader, syms []byte, codePrefix *[12]uint32) (int, error) {
+	forcedBits := int(br.readBitsNoRefill(2))
+	numSymbols := int(br.readBitsNoRefill(8)) + 1
+	fluff := br.readFluff(numSymbols)
+
+	var codeLen [512 + 16]byte
+	br2 := br.toBitReader2()
+	if !decodeGolombRiceLengths(codeLen[:numSymbols+fluff], br2) {
+		return 0, ErrorCorruptedData
+	}
+	if !decodeGolombRiceBits(codeLen[:numSymbols], forcedBits, br2) {
+		return 0, ErrorCorruptedData
+	}
+	br.resetFrom(br2)
+
+	runningSum := 0x1e
+	for i := 0; i < numSymbols; i++ {
+		v := int(codeLen[i])
+		v = -(v & 1) ^ (v >> 1)
+		l := v + runningSum>>2 + 1
+		if l < 1 || l > 11 {
+			return 0, ErrorCorruptedData
+		}
+		codeLen[i] = byte(l)
+		runningSum += v
+	}
+
+	ranges, err := convertToRanges(numSymbols, fluff, codeLen[numSymbols:numSymbols+fluff], br)
+	if err != nil {
+		return 0, err
+	}
+
+	i := 0
+	for _, r := range ranges {
+		sym := r.symbol
+		for n := r.num; n > 0; n-- {
+			l := codeLen[i]
+			syms[codePrefix[l]] = byte(sym)
+			codePrefix[l]++
+			i++
+			sym++
+		}
+	}
+
+	return numSymbols, nil
+}
+
+type huffRange struct {
+	symbol int
+	num    int
+}
+
+// convertToRanges reads the runs of used symbols, p is the number of run lengths stored in symLen.
+func convertToRanges(numSymbols int, p int, symLen []byte, br *bitReader) ([]huffRange, error) {
+	numRanges := p >> 1
+	symIdx := 0
+
+	if p&1 != 0 {
+		br.refill()
+		v := int(symLen[0])
+		symLen = symLen[1:]
+		if v >= 8 {
+			return nil, ErrorCorruptedData
+		}
+		symIdx = int(br.readBitsNoRefill(v+1)) + 1<<(v+1) - 1
+	}
+
+	symsUsed := 0
+	ranges := make([]huffRange, 0, numRanges+1)
+	for i := 0; i < numRanges; i++ {
+		br.refill()
+		v := int(symLen[0])
+		if v >= 9 {
+			return nil, ErrorCorruptedData
+		}
+		num := int(br.readBitsNoRefillZero(v)) + 1<<v
+		v = int(symLen[1])
+		if v >= 8 {
+			return nil, ErrorCorruptedData
+		}
+		space := int(br.readBitsNoRefill(v+1)) + 1<<(v+1) - 1
+		ranges = append(ranges, huffRange{
+			symbol: symIdx,
+			num:    num,
+		})
+		symsUsed += num
+		symIdx += num + space
+		symLen = symLen[2:]
+	}
+
+	if symIdx >= 256 || symsUsed >= numSymbols || symIdx+numSymbols-symsUsed > 256 {
+		return nil, ErrorCorruptedData
+	}
+
+	ranges = append(ranges, huffRange{
+		symbol: symIdx,
+		num:    numSymbols - symsUsed,
+	})
+
+	return ranges, nil
+}
+
+// makeHuffLut builds a lookup table indexed by the next 11 bits of the stream, least significant bit first.
+func makeHuffLut(codePrefix *[12]uint32, syms []byte) (*huffLut, error) {
+	lut := &huffLut{}
+
+	slot := 0
+	for i := 1; i < 11; i++ {
+		start := int(codePrefixOrg[i])
+		count := int(codePrefix[i]) - start
+		if count == 0 {
+			continue
+		}
+
+		step := 1 << (11 - i)
+		num := count << (11 - i)
+		if slot+num > 2048 {
+			return nil, ErrorCorruptedData
+		}
+		for j := 0; j < num; j++ {
+			lut.bits2len[slot+j] = byte(i)
+		}
+		for j := 0; j < count; j++ {
+			for k := 0; k < step; k++ {
+				lut.bits2sym[slot+j*step+k] = syms[start+j]
+			}
+		}
+		slot += num
+	}
+
+	count := int(codePrefix[11] - codePrefixOrg[11])
+	if count != 0 {
+		if slot+count > 2048 {
+			return nil, ErrorCorruptedData
+		}
+		for j := 0; j < count; j++ {
+			lut.bits2len[slot+j] = 11
+		}
+		copy(lut.bits2sym[slot:], syms[codePrefixOrg[11]:int(codePrefixOrg[11])+count])
+		slot += count
+	}
+
+	if slot != 2048 {
+		return nil, ErrorCorruptedData
+	}
+
+	rev := &huffLut{}
+	for i := 0; i < 2048; i++ {
+		j := bits.Reverse16(uint16(i)) >> 5
+		rev.bits2len[i] = lut.bits2len[j]
+		rev.bits2sym[i] = lut.bits2sym[j]
+	}
+
+	return rev, nil
+}
+
+type tansData struct {
+	a []byte
+	b []uint32
+}
+
+type tansLutEnt struct {
+	x      uint32
+	bitsX  uint8
+	symbol uint8
+	w      uint16
+}
+
+// decodeTans decodes a tANS block with five interleaved states.
+func decodeTans(src []byte, dst []byte) (int, error) {
+	if len(src) < 8 || len(dst) < 5 {
+		return 0, ErrorCorruptedData
+	}
+
+	br := newBitReader(src)
+	if br.readBitNoRefill() != 0 {
+		return 0, ErrorCorruptedData
+	}
+
+	lBits := int(br.readBitsNoRefill(2)) + 8
+
+	td, err := decodeTansTable(br, lBits)
+	if err != nil {
+		return 0, err
+	}
+
+	pos := br.bytePos()
+	if pos >= len(src) {
+		return 0, ErrorCorruptedData
+	}
+
+	lut, err := initTansLut(td, lBits)
+	if err != nil {
+		return 0, err
+	}
+
+	lMask := uint32(1)<<lBits - 1
+	end := len(src)
+	bitsF := readLE32(src, pos)
+	pos += 4
+	bitsB := readBE32(src, end-4)
+	end -= 4
+	bitposF, bitposB := 32, 32
+
+	var states [5]uint32
+	states[0] = bitsF & lMask
+	states[1] = bitsB & lMask
+	bitsF >>= lBits
+	bitposF -= lBits
+	bitsB >>= lBits
+	bitposB -= lBits
+
+	states[2] = bitsF & lMask
+	states[3] = bitsB & lMask
+	bitsF >>= lBits
+	bitposF -= lBits
+	bitsB >>= lBits
+	bitposB -= lBits
+
+	bitsF |= readLE32(src, pos) << bitposF
+	pos += (31 - bitposF) >> 3
+	bitposF |= 24
+
+	states[4] = bitsF & lMask
+	bitsF >>= lBits
+	bitposF -= lBits
+
+	ptrF := pos - bitposF>>3
+	bitposF &= 7
+	ptrB := end + bitposB>>3
+	bitposB &= 7
+
+	if ptrF > ptrB {
+		return 0, ErrorCorruptedData
+	}
+
+	steps := [...]struct {
+		refill  bool
+		forward bool
+		state   int
+	}{
+		{true, true, 0}, {false, true, 1}, {true, true, 2}, {false, true, 3}, {true, true, 4},
+		{true, false, 0}, {false, false, 1}, {true, false, 2}, {false, false, 3}, {true, false, 4},
+	}
+
+	dstEnd := len(dst) - 5
+	i := 0
+loop:
+	for i < dstEnd {
+		for _, step := range steps {
+			state := states[step.state]
+			if state >= uint32(len(lut)) {
+				return 0, ErrorCorruptedData
+			}
+			e := &lut[state]
+			dst[i] = e.symbol
+			i++
+
+			if step.forward {
+				if step.refill {
+					bitsF |= readLE32(src, ptrF) << bitposF
+					ptrF += (31 - bitposF) >> 3
+					bitposF |= 24
+				}
+				bitposF -= int(e.bitsX)
+				states[step.state] = bitsF&e.x + uint32(e.w)
+				bitsF >>= e.bitsX
+			} else {
+				if step.refill {
+					bitsB |= readBE32(src, ptrB-4) << bitposB
+					ptrB -= (31 - bitposB) >> 3
+					bitposB |= 24
+				}
+				bitposB -= int(e.bitsX)
+				states[step.state] = bitsB&e.x + uint32(e.w)
+				bitsB >>= e.bitsX
+			}
+
+			if i >= dstEnd {
+				break loop
+			}
+		}
+	}
+
+	if ptrB-ptrF+bitposF>>3+bitposB>>3 != 0 {
+		return 0, ErrorCorruptedData
+	}
+
+	for j, state := range states {
+		if state&^0xff != 0 {
+			return 0, ErrorCorruptedData
+		}
+		dst[dstEnd+j] = byte(state)
+	}
+
+	return len(src), nil
+}
+
+func decodeTansTable(br *bitReader, lBits int) (*tansData, error) {
+	td := &tansData{}
+	l := 1 << lBits
+
+	br.refill()
+	if br.readBitNoRefill() != 0 {
+		q := int(br.readBitsNoRefill(3))
+		numSymbols := int(br.readBitsNoRefill(8)) + 1
+		if numSymbols < 2 {
+			return nil, ErrorCorruptedData
+		}
+		fluff := br.readFluff(numSymbols)
+		totalRiceValues := fluff + numSymbols
+
+		var rice [512 + 16]byte
+		br2 := br.toBitReader2()
+		if !decodeGolombRiceLengths(rice[:totalRiceValues], br2) {
+			return nil, ErrorCorruptedData
+		}
+		br.resetFrom(br2)
+
+		ranges, err := convertToRanges(numSymbols, fluff, rice[numSymbols:totalRiceValues], br)
+		if err != nil {
+			return nil, err
+		}
+		br.refill()
+
+		cur := 0
+		average := 6
+		sum := 0
+		for _, r := range ranges {
+			symbol := r.symbol
+			for n := r.num; n > 0; n-- {
+				br.refill()
+
+				nExtra := q + int(rice[cur])
+				cur++
+				if nExtra > 15 {
+					return nil, ErrorCorruptedData
+				}
+				v := int(br.readBitsNoRefillZero(nExtra)) + 1<<nExtra - 1<<q
+
+				averageDiv4 := average >> 2
+				limit := 2 * averageDiv4
+				if v <= limit {
+					v = averageDiv4 + (-(v & 1) ^ (v >> 1))
+				}
+				if limit > v {
+					limit = v
+				}
+				v++
+				average += limit - averageDiv4
+
+				if v == 1 {
+					td.a = append(td.a, byte(symbol))
+				} else if v >= 2 {
+					td.b = append(td.b, uint32(symbol<<16+v))
+				}
+				sum += v
+				symbol++
+			}
+		}
+
+		if sum != l {
+			return nil, ErrorCorruptedData
+		}
+
+		return td, nil
+	}
+
+	var seen [256]bool
+
+	count := int(br.readBitsNoRefill(3)) + 1
+	bitsPerSym := bits.Len(uint(lBits))
+	maxDeltaBits := int(br.readBitsNoRefill(bitsPerSym))
+	if maxDeltaBits == 0 || maxDeltaBits > lBits {
+		return nil, ErrorCorruptedData
+	}
+
+	weight := 0
+	totalWeights := 0
+	for ; count > 0; count-- {
+		br.refill()
+
+		sym := int(br.readBitsNoRefill(8))
+		if seen[sym] {
+			return nil, ErrorCorruptedData
+		}
+
+		delta := int(br.readBitsNoRefill(maxDeltaBits))
+		weight += delta
+		if weight == 0 {
+			return nil, ErrorCorruptedData
+		}
+
+		seen[sym] = true
+		if weight == 1 {
+			td.a = append(td.a, byte(sym))
+		} else {
+			td.b = append(td.b, uint32(sym<<16+weight))
+		}
+		totalWeights += weight
+	}
+
+	br.refill()
+	sym := int(br.readBitsNoRefill(8))
+	if seen[sym] {
+		return nil, ErrorCorruptedData
+	}
+
+	if l-totalWeights < weight || l-totalWeights <= 1 {
+		return nil, ErrorCorruptedData
+	}
+	td.b = append(td.b, uint32(sym<<16+l-totalWeights))
+
+	slices.Sort(td.a)
+	slices.Sort(td.b)
+
+	return td, nil
+}
+
+func initTansLut(td *tansData, lBits int) ([]tansLutEnt, error) {
+	l := 1 << lBits
+	lut := make([]tansLutEnt, l)
+
+	slotsLeft := l - len(td.a)
+	if slotsLeft < 0 {
+		return nil, ErrorCorruptedData
+	}
+
+	var pointers [4]int
+	sa := slotsLeft >> 2
+	sb := sa
+	if slotsLeft&3 > 0 {
+		sb++
+	}
+	pointers[1] = sb
+	sb += sa
+	if slotsLeft&3 > 1 {
+		sb++
+	}
+	pointers[2] = sb
+	sb += sa
+	if slotsLeft&3 > 2 {
+		sb++
+	}
+	pointers[3] = sb
+
+	for i, sym := range td.a {
+		lut[slotsLeft+i] = tansLutEnt{
+			x:      uint32(l - 1),
+			bitsX:  uint8(lBits),
+			symbol: sym,
+		}
+	}
+
+	weightsSum := 0
+	for _, b := range td.b {
+		weight := int(b & 0xffff)
+		symbol := uint8(b >> 16)
+
+		if weight > 4 {
+			symBits := bits.Len(uint(weight)) - 1
+			z := lBits - symBits
+			le := tansLutEnt{
+				x:      uint32(1)<<z - 1,
+				bitsX:  uint8(z),
+				symbol: symbol,
+				w:      uint16((l - 1) & (weight << z)),
+			}
+			whatToAdd := 1 << z
+			x := 1<<(symBits+1) - weight
+
+			for j := 0; j < 4; j++ {
+				dst := pointers[j]
+
+				y := (weight + (weightsSum-j-1)&3) >> 2
+				if dst+y > l {
+					return nil, ErrorCorruptedData
+				}
+				if x >= y {
+					for n := y; n > 0; n-- {
+						lut[dst] = le
+						dst++
+						le.w += uint16(whatToAdd)
+					}
+					x -= y
+				} else {
+					for n := x; n > 0; n-- {
+						lut[dst] = le
+						dst++
+						le.w += uint16(whatToAdd)
+					}
+					z--
+
+					whatToAdd >>= 1
+					le.bitsX = uint8(z)
+					le.w = 0
+					le.x >>= 1
+					for n := y - x; n > 0; n-- {
+						lut[dst] = le
+						dst++
+						le.w += uint16(whatToAdd)
+					}
+					x = weight
+				}
+				pointers[j] = dst
+			}
+		} else {
+			if weight <= 0 {
+				return nil, ErrorCorruptedData
+			}
+			mask := uint32(1<<weight-1) << (weightsSum & 3)
+			mask |= mask >> 4
+			ww := weight
+			for n := weight; n > 0; n-- {
+				idx := bits.TrailingZeros32(mask)
+				mask &= mask - 1
+				dst := pointers[idx]
+				if dst >= l {
+					return nil, ErrorCorruptedData
+				}
+				pointers[idx]++
+				weightBits := bits.Len(uint(ww)) - 1
+				lut[dst] = tansLutEnt{
+					x:      uint32(1)<<(lBits-weightBits) - 1,
+					bitsX:  uint8(lBits - weightBits),
+					symbol: symbol,
+					w:      uint16((l - 1) & (ww << (lBits - weightBits))),
+				}
+				ww++
+			}
+		}
+		weightsSum += weight
+	}
+
+	return lut, nil
+}
+
+// decodeRLE decodes a run-length block. Commands are read backwards from the end, literals forwards from the start.
+func decodeRLE(src []byte, dst []byte) (int, error) {
+	if len(src) <= 1 {
+		if len(src) != 1 {
+			return 0, ErrorCorruptedData
+		}
+		for i := range dst {
+			dst[i] = src[0]
+		}
+		return 1, nil
+	}
+
+	cmd := src[1:]
+	if src[0] != 0 {
+		decoded, n, err := decodeBytes(src, nil, maxBlockSize, true)
+		if err != nil {
+			return 0, err
+		}
+		cmd = append(decoded[:len(decoded):len(decoded)], src[n:]...)
+	}
+
+	cmdPos := 0
+	cmdEnd := len(cmd)
+	pos := 0
+	rleByte := byte(0)
+
+	for cmdPos < cmdEnd {
+		c := uint32(cmd[cmdEnd-1])
+		var bytesToCopy, bytesToRle int
+
+		if c-1 >= 0x2f {
+			cmdEnd--
+			bytesToCopy = int(^c & 0xf)
+			bytesToRle = int(c >> 4)
+		} else if c == 1 {
+			if cmdEnd-cmdPos < 2 {
+				return 0, ErrorCorruptedData
+			}
+			rleByte = cmd[cmdPos]
+			cmdPos++
+			cmdEnd--
+			continue
+		} else {
+			if cmdEnd-cmdPos < 2 {
+				return 0, ErrorCorruptedData
+			}
+			v := int(binary.LittleEndian.Uint16(cmd[cmdEnd-2:]))
+			cmdEnd -= 2
+
+			switch {
+			case c >= 0x10:
+				v -= 4096
+				if v < 0 {
+					return 0, ErrorCorruptedData
+				}
+				bytesToCopy = v & 0x3f
+				bytesToRle = v >> 6
+			case c >= 9:
+				bytesToRle = (v - 0x8ff) * 128
+			default:
+				bytesToCopy = (v - 511) * 64
+			}
+			if bytesToCopy < 0 || bytesToRle < 0 {
+				return 0, ErrorCorruptedData
+			}
+		}
+
+		if len(dst)-pos < bytesToCopy+bytesToRle || cmdEnd-cmdPos < bytesToCopy {
+			return 0, ErrorCorruptedData
+		}
+		copy(dst[pos:], cmd[cmdPos:cmdPos+bytesToCopy])
+		cmdPos += bytesToCopy
+		pos += bytesToCopy
+		for i := 0; i < bytesToRle; i++ {
+			dst[pos+i] = rleByte
+		}
+		pos += bytesToRle
+	}
+
+	if cmdEnd != cmdPos || pos != len(dst) {
+		return 0, ErrorCorruptedData
+	}
+
+	return len(src), nil
+}
+
+// decodeRecursive decodes a block made of several entropy coded blocks.
+func decodeRecursive(src []byte, dst []byte) (int, error) {
+	if len(src) < 6 {
+		return 0, ErrorCorruptedData
+	}
+
+	n := int(src[0] & 0x7f)
+	if n < 2 {
+		return 0, ErrorCorruptedData
+	}
+
+	if src[0]&0x80 == 0 {
+		pos := 1
+		out := 0
+		for ; n > 0; n-- {
+			decoded, used, err := decodeBytes(src[pos:], dst[out:], len(dst)-out, true)
+			if err != nil {
+				return 0, err
+			}
+			out += len(decoded)
+			pos += used
+		}
+
+		if out != len(dst) {
+			return 0, ErrorCorruptedData
+		}
+
+		return pos, nil
+	}
+
+	_, total, used, err := decodeMultiArray(src, dst, 1, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if total != len(dst) {
+		return 0, ErrorCorruptedData
+	}
+
+	return used, nil
+}
+
+// decodeMultiArray decodes arrayCount arrays into dst that are assembled from intervals of several entropy coded
+// blocks. It returns the arrays, their total size and the number of bytes read from src.
+func decodeMultiArray(src []byte, dst []byte, arrayCount int, forceCopy bool) ([][]byte, int, int, error) {
+	if len(src) < 4 {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	numArraysInFile := int(src[0])
+	pos := 1
+	if numArraysInFile&0x80 == 0 {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+	numArraysInFile &= 0x3f
+
+	arrays := make([][]byte, arrayCount)
+	out := 0
+
+	if numArraysInFile == 0 {
+		for i := range arrays {
+			decoded, used, err := decodeBytes(src[pos:], dst[out:], len(dst)-out, forceCopy)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			arrays[i] = decoded
+			out += len(decoded)
+			pos += used
+		}
+
+		return arrays, out, pos, nil
+	}
+
+	entropyArrays := make([][]byte, numArraysInFile)
+	totalSize := 0
+	for i := range entropyArrays {
+		decoded, used, err := decodeBytes(src[pos:], nil, len(dst)-totalSize, forceCopy)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		entropyArrays[i] = decoded
+		totalSize += len(decoded)
+		pos += used
+	}
+
+	if len(src)-pos < 3 {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	q := int(binary.LittleEndian.Uint16(src[pos:]))
+	pos += 2
+
+	numIndexes, err := getBlockSize(src[pos:], totalSize)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	numLens := numIndexes - arrayCount
+	if numLens < 1 {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	var intervalLenLog2, intervalIndexes []byte
+	if q&0x8000 != 0 {
+		decoded, used, err := decodeBytes(src[pos:], nil, numIndexes, false)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if len(decoded) != numIndexes {
+			return nil, 0, 0, ErrorCorruptedData
+		}
+		pos += used
+
+		intervalLenLog2 = make([]byte, numIndexes)
+		intervalIndexes = make([]byte, numIndexes)
+		for i, t := range decoded {
+			intervalLenLog2[i] = t >> 4
+			intervalIndexes[i] = t & 0xf
+		}
+
+		numLens = numIndexes
+	} else {
+		var used int
+		intervalIndexes, used, err = decodeBytes(src[pos:], nil, numIndexes, false)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if len(intervalIndexes) != numIndexes {
+			return nil, 0, 0, ErrorCorruptedData
+		}
+		pos += used
+
+		intervalLenLog2, used, err = decodeBytes(src[pos:], nil, numLens, false)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if len(intervalLenLog2) != numLens {
+			return nil, 0, 0, ErrorCorruptedData
+		}
+		pos += used
+
+		for _, v := range intervalLenLog2 {
+			if v > 16 {
+				return nil, 0, 0, ErrorCorruptedData
+			}
+		}
+	}
+
+	varBitsCompLen := q & 0x3fff
+	if len(src)-pos < varBitsCompLen {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	f := pos
+	bitsF := uint32(0)
+	bitposF := 24
+
+	srcEndActual := pos + varBitsCompLen
+
+	b := srcEndActual
+	bitsB := uint32(0)
+	bitposB := 24
+
+	decodedIntervals := make([]uint32, numLens)
+	i := 0
+	for ; i+2 <= numLens; i += 2 {
+		bitsF |= readBE32(src, f) >> (24 - bitposF)
+		f += (bitposF + 7) >> 3
+
+		bitsB |= readLE32(src, b-4) >> (24 - bitposB)
+		b -= (bitposB + 7) >> 3
+
+		numBitsF := int(intervalLenLog2[i])
+		numBitsB := int(intervalLenLog2[i+1])
+
+		bitsF = bits.RotateLeft32(bitsF|1, numBitsF)
+		bitposF += numBitsF - 8*((bitposF+7)>>3)
+
+		bitsB = bits.RotateLeft32(bitsB|1, numBitsB)
+		bitposB += numBitsB - 8*((bitposB+7)>>3)
+
+		maskF := uint32(2)<<numBitsF - 1
+		maskB := uint32(2)<<numBitsB - 1
+
+		decodedIntervals[i] = bitsF & maskF
+		bitsF &^= maskF
+
+		decodedIntervals[i+1] = bitsB & maskB
+		bitsB &^= maskB
+	}
+
+	if i < numLens {
+		bitsF |= readBE32(src, f) >> (24 - bitposF)
+		numBitsF := int(intervalLenLog2[i])
+		bitsF = bits.RotateLeft32(bitsF|1, numBitsF)
+		decodedIntervals[i] = bitsF & (uint32(2)<<numBitsF - 1)
+	}
+
+	if intervalIndexes[numIndexes-1] != 0 {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	indi := 0
+	leni := 0
+	incrementLeni := 0
+	if q&0x8000 != 0 {
+		incrementLeni = 1
+	}
+
+	for arri := range arrays {
+		start := out
+		if indi >= numIndexes {
+			return nil, 0, 0, ErrorCorruptedData
+		}
+
+		for {
+			source := int(intervalIndexes[indi])
+			indi++
+			if source == 0 {
+				break
+			}
+			if source > numArraysInFile || leni >= numLens {
+				return nil, 0, 0, ErrorCorruptedData
+			}
+
+			curLen := int(decodedIntervals[leni])
+			leni++
+			if curLen > len(entropyArrays[source-1]) || curLen > len(dst)-out {
+				return nil, 0, 0, ErrorCorruptedData
+			}
+			copy(dst[out:], entropyArrays[source-1][:curLen])
+			entropyArrays[source-1] = entropyArrays[source-1][curLen:]
+			out += curLen
+		}
+		leni += incrementLeni
+		arrays[arri] = dst[start:out]
+	}
+
+	if indi != numIndexes || leni != numLens {
+		return nil, 0, 0, ErrorCorruptedData
+	}
+
+	for _, entropyArray := range entropyArrays {
+		if len(entropyArray) != 0 {
+			return nil, 0, 0, ErrorCorruptedData
+		}
+	}
+
+	return arrays, totalSize, srcEndActual, nil
+}
@@ -0,0 +1,17 @@
+package oodle
+
+import (
+	"testing"
+)
+
+func FuzzDecompressNative(f *testing.F) {
+	for _, vector := range decodeVectors {
+		f.Add(mustDecodeHex(vector.data), uint32(len(vector.want)))
+	}
+	f.Add([]byte("\x8c\x060\x00\x10 \x000\x00\a\x00\x800000000000"), uint32(18))
+
+	f.Fuzz(func(t *testing.T, data []byte, uncompressedSize uint32) {
+		// sizes above a few quanta do not reach other code
+		DecompressNative(data, int64(uncompressedSize%(4*0x40000)))
+	})
+}
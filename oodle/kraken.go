@@ -0,0 +1,373 @@
+package oodle
+
+import (
+	"math/bits"
+)
+
+type krakenLzTable struct {
+	cmdStream  []byte
+	litStream  []byte
+	offsStream []int32
+	lenStream  []int32
+}
+
+// decodeKrakenQuantum decodes src into dst[start:end] in chunks of 128 KiB and returns the number of bytes read.
+func decodeKrakenQuantum(dst []byte, start int, end int, src []byte) (int, error) {
+	pos := 0
+	for start < end {
+		dstCount := min(end-start, 0x20000)
+		if len(src)-pos < 4 {
+			return 0, ErrorCorruptedData
+		}
+
+		chunkHeader := int(src[pos])<<16 | int(src[pos+1])<<8 | int(src[pos+2])
+		var srcUsed int
+		if chunkHeader&0x800000 == 0 {
+			// entropy coded without matches
+			decoded, used, err := decodeBytes(src[pos:], dst[start:start+dstCount], dstCount, true)
+			if err != nil {
+				return 0, err
+			}
+			if len(decoded) != dstCount {
+				return 0, ErrorCorruptedData
+			}
+			srcUsed = used
+		} else {
+			pos += 3
+			srcUsed = chunkHeader & 0x7ffff
+			mode := (chunkHeader >> 19) & 0xf
+			if len(src)-pos < srcUsed {
+				return 0, ErrorCorruptedData
+			}
+
+			if srcUsed < dstCount {
+				lz, err := readKrakenLzTable(mode, src[pos:pos+srcUsed], dst, start, dstCount)
+				if err != nil {
+					return 0, err
+				}
+
+				err = processKrakenLzRuns(mode, dst, start, dstCount, lz)
+				if err != nil {
+					return 0, err
+				}
+			} else if srcUsed > dstCount || mode != 0 {
+				return 0, ErrorCorruptedData
+			} else {
+				copy(dst[start:start+dstCount], src[pos:pos+dstCount])
+			}
+		}
+
+		pos += srcUsed
+		start += dstCount
+	}
+
+	return pos, nil
+}
+
+func readKrakenLzTable(mode int, src []byte, dst []byte, offset int, dstSize int) (*krakenLzTable, error) {
+	if mode > 1 || len(src) < 13 {
+		return nil, ErrorCorruptedData
+	}
+
+	pos := 0
+	if offset == 0 {
+		if dstSize < 8 {
+			return nil, ErrorCorruptedData
+		}
+		copy(dst[:8], src[:8])
+		pos = 8
+	}
+
+	if src[pos]&0x80 != 0 {
+		// excess bytes are not supported
+		return nil, ErrorCorruptedData
+	}
+
+	lz := &krakenLzTable{}
+
+	var n int
+	var err error
+	lz.litStream, n, err = decodeBytes(src[pos:], nil, dstSize, false)
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+
+	lz.cmdStream, n, err = decodeBytes(src[pos:], nil, dstSize, false)
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+
+	if len(src)-pos < 3 {
+		return nil, ErrorCorruptedData
+	}
+
+	offsScaling := 0
+	var packedOffsStream, packedOffsStreamExtra []byte
+	if src[pos]&0x80 != 0 {
+		// offsets are coded with two tables
+		offsScaling = int(src[pos]) - 127
+		pos++
+
+		packedOffsStream, n, err = decodeBytes(src[pos:], nil, len(lz.cmdStream), false)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+
+		if offsScaling != 1 {
+			packedOffsStreamExtra, n, err = decodeBytes(src[pos:], nil, len(packedOffsStream), false)
+			if err != nil {
+				return nil, err
+			}
+			if len(packedOffsStreamExtra) != len(packedOffsStream) {
+				return nil, ErrorCorruptedData
+			}
+			pos += n
+		}
+	} else {
+		packedOffsStream, n, err = decodeBytes(src[pos:], nil, len(lz.cmdStream), false)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+	}
+
+	packedLenStream, n, err := decodeBytes(src[pos:], nil, dstSize>>2, false)
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+
+	lz.offsStream = make([]int32, len(packedOffsStream))
+	lz.lenStream = make([]int32, len(packedLenStream))
+
+	err = unpackOffsets(src[pos:], packedOffsStream, packedOffsStreamExtra, offsScaling, packedLenStream, lz.offsStream, lz.lenStream)
+	if err != nil {
+		return nil, err
+	}
+
+	return lz, nil
+}
+
+// unpackOffsets reads the match offsets and the long lengths from two bit streams, one read from the start of src
+// and one from the end. The streams have to meet exactly.
+func unpackOffsets(src []byte, packedOffsStream []byte, packedOffsStreamExtra []byte, multiDistScale int, packedLenStream []byte, offsStream []int32, lenStream []int32) error {
+	a := newBitReader(src)
+	b := newBackwardBitReader(src)
+
+	if b.bits < 0x2000 {
+		return ErrorCorruptedData
+	}
+	n := bits.LeadingZeros32(b.bits)
+	b.bitpos += n
+	b.bits <<= n
+	b.refill()
+	n++
+	u32LenStreamSize := int(b.bits>>(32-n)) - 1
+	b.bitpos += n
+	b.bits <<= n
+	b.refill()
+
+	if multiDistScale == 0 {
+		for i, v := range packedOffsStream {
+			if i&1 == 0 {
+				offsStream[i] = -int32(a.readDistance(uint32(v)))
+			} else {
+				offsStream[i] = -int32(b.readDistance(uint32(v)))
+			}
+		}
+	} else {
+		for i, v := range packedOffsStream {
+			cmd := uint32(v)
+			if cmd>>3 > 26 {
+				return ErrorCorruptedData
+			}
+			br := a
+			if i&1 != 0 {
+				br = b
+			}
+			offs := (8+cmd&7)<<(cmd>>3) | br.readMoreThan24Bits(int(cmd>>3))
+			offsStream[i] = 8 - int32(offs)
+		}
+
+		if multiDistScale != 1 {
+			for i := range offsStream {
+				offsStream[i] = int32(multiDistScale)*offsStream[i] - int32(packedOffsStreamExtra[i])
+			}
+		}
+	}
+
+	if u32LenStreamSize > 512 {
+		return ErrorCorruptedData
+	}
+
+	u32LenStream := make([]uint32, u32LenStreamSize)
+	for i := range u32LenStream {
+		br := a
+		if i&1 != 0 {
+			br = b
+		}
+		v, ok := br.readLength()
+		if !ok {
+			return ErrorCorruptedData
+		}
+		u32LenStream[i] = v
+	}
+
+	a.p -= (24 - a.bitpos) >> 3
+	b.p += (24 - b.bitpos) >> 3
+	if a.p != b.p {
+		return ErrorCorruptedData
+	}
+
+	u := 0
+	for i, v := range packedLenStream {
+		length := uint32(v)
+		if length == 255 {
+			if u >= len(u32LenStream) {
+				return ErrorCorruptedData
+			}
+			length = u32LenStream[u] + 255
+			u++
+		}
+		lenStream[i] = int32(length + 3)
+	}
+
+	if u != len(u32LenStream) {
+		return ErrorCorruptedData
+	}
+
+	return nil
+}
+
+// processKrakenLzRuns executes the commands of a chunk. Mode 0 adds literals to the bytes at the last offset, mode 1
+// copies them as is.
+func processKrakenLzRuns(mode int, dst []byte, offset int, dstSize int, lz *krakenLzTable) error {
+	pos := offset
+	end := offset + dstSize
+	if offset == 0 {
+		pos += 8
+	}
+
+	lit := lz.litStream
+	offs := lz.offsStream
+	lens := lz.lenStream
+
+	var recentOffs [7]int32
+	recentOffs[3] = -8
+	recentOffs[4] = -8
+	recentOffs[5] = -8
+	lastOffset := int32(-8)
+
+	for _, f := range lz.cmdStream {
+		litLen := int(f & 3)
+		offsIndex := int(f >> 6)
+		matchLen := int(f>>2) & 0xf
+
+		if litLen == 3 {
+			if len(lens) == 0 {
+				return ErrorCorruptedData
+			}
+			litLen = int(lens[0])
+			lens = lens[1:]
+		}
+
+		if len(offs) > 0 {
+			recentOffs[6] = offs[0]
+		}
+
+		if litLen > len(lit) || litLen > end-pos {
+			return ErrorCorruptedData
+		}
+		err := copyLiterals(dst, pos, lit[:litLen], int(lastOffset), mode == 0)
+		if err != nil {
+			return err
+		}
+		pos += litLen
+		lit = lit[litLen:]
+
+		off := recentOffs[offsIndex+3]
+		recentOffs[offsIndex+3] = recentOffs[offsIndex+2]
+		recentOffs[offsIndex+2] = recentOffs[offsIndex+1]
+		recentOffs[offsIndex+1] = recentOffs[offsIndex]
+		recentOffs[3] = off
+		lastOffset = off
+
+		if offsIndex == 3 {
+			if len(offs) == 0 {
+				return ErrorCorruptedData
+			}
+			offs = offs[1:]
+		}
+
+		if matchLen != 15 {
+			matchLen += 2
+		} else {
+			if len(lens) == 0 {
+				return ErrorCorruptedData
+			}
+			matchLen = 14 + int(lens[0])
+			lens = lens[1:]
+		}
+
+		if matchLen > end-pos {
+			return ErrorCorruptedData
+		}
+		err = copyMatch(dst, pos, int(off), matchLen)
+		if err != nil {
+			return err
+		}
+		pos += matchLen
+	}
+
+	if len(offs) != 0 || len(lens) != 0 {
+		return ErrorCorruptedData
+	}
+
+	if end-pos != len(lit) {
+		return ErrorCorruptedData
+	}
+
+	return copyLiterals(dst, pos, lit, int(lastOffset), mode == 0)
+}
+
+// copyLiterals stores lit at dst[pos:]. Delta literals are added to the bytes at offset.
+func copyLiterals(dst []byte, pos int, lit []byte, offset int, delta bool) error {
+	if pos+len(lit) > len(dst) {
+		return ErrorCorruptedData
+	}
+
+	if !delta {
+		copy(dst[pos:], lit)
+		return nil
+	}
+
+	if offset >= 0 || pos+offset < 0 {
+		return ErrorCorruptedData
+	}
+	for i, b := range lit {
+		dst[pos+i] = b + dst[pos+i+offset]
+	}
+
+	return nil
+}
+
+// copyMatch copies length bytes from dst[pos+offset:] to dst[pos:], offset is negative and the ranges can overlap.
+func copyMatch(dst []byte, pos int, offset int, length int) error {
+	if offset >= 0 || pos+offset < 0 || pos+length > len(dst) {
+		return ErrorCorruptedData
+	}
+
+	if -offset >= length {
+		copy(dst[pos:pos+length], dst[pos+offset:])
+		return nil
+	}
+
+	for i := 0; i < length; i++ {
+		dst[pos+i] = dst[pos+i+offset]
+	}
+
+	return nil
+}
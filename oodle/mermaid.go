@@ -0,0 +1,404 @@
+package oodle
+
+import (
+	"encoding/binary"
+)
+
+type mermaidLzTable struct {
+	cmdStream     []byte
+	cmdStream2Pos int
+
+	litStream    []byte
+	lengthStream []byte
+
+	off16Stream  []uint16
+	off32Stream1 []uint32
+	off32Stream2 []uint32
+}
+
+// decodeMermaidQuantum decodes src into dst[start:end] in chunks of 128 KiB and returns the number of bytes read.
+// Selkie streams use the same format.
+func decodeMermaidQuantum(dst []byte, start int, end int, src []byte) (int, error) {
+	pos := 0
+	for start < end {
+		dstCount := min(end-start, 0x20000)
+		if len(src)-pos < 4 {
+			return 0, ErrorCorruptedData
+		}
+
+		chunkHeader := int(src[pos])<<16 | int(src[pos+1])<<8 | int(src[pos+2])
+		var srcUsed int
+		if chunkHeader&0x800000 == 0 {
+			// entropy coded without matches
+			decoded, used, err := decodeBytes(src[pos:], dst[start:start+dstCount], dstCount, true)
+			if err != nil {
+				return 0, err
+			}
+			if len(decoded) != dstCount {
+				return 0, ErrorCorruptedData
+			}
+			srcUsed = used
+		} else {
+			pos += 3
+			srcUsed = chunkHeader & 0x7ffff
+			mode := (chunkHeader >> 19) & 0xf
+			if len(src)-pos < srcUsed {
+				return 0, ErrorCorruptedData
+			}
+
+			if srcUsed < dstCount {
+				lz, err := readMermaidLzTable(mode, src[pos:pos+srcUsed], dst, start, dstCount)
+				if err != nil {
+					return 0, err
+				}
+
+				err = lz.process(mode, dst, start, dstCount)
+				if err != nil {
+					return 0, err
+				}
+			} else if srcUsed > dstCount || mode != 0 {
+				return 0, ErrorCorruptedData
+			} else {
+				copy(dst[start:start+dstCount], src[pos:pos+dstCount])
+			}
+		}
+
+		pos += srcUsed
+		start += dstCount
+	}
+
+	return pos, nil
+}
+
+func readMermaidLzTable(mode int, src []byte, dst []byte, offset int, dstSize int) (*mermaidLzTable, error) {
+	if mode > 1 || len(src) < 10 {
+		return nil, ErrorCorruptedData
+	}
+
+	pos := 0
+	if offset == 0 {
+		if dstSize < 8 {
+			return nil, ErrorCorruptedData
+		}
+		copy(dst[:8], src[:8])
+		pos = 8
+	}
+
+	lz := &mermaidLzTable{}
+
+	var n int
+	var err error
+	lz.litStream, n, err = decodeBytes(src[pos:], nil, dstSize, false)
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+
+	lz.cmdStream, n, err = decodeBytes(src[pos:], nil, dstSize, false)
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+
+	lz.cmdStream2Pos = len(lz.cmdStream)
+	if dstSize > 0x10000 {
+		if len(src)-pos < 2 {
+			return nil, ErrorCorruptedData
+		}
+		lz.cmdStream2Pos = int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+		if lz.cmdStream2Pos > len(lz.cmdStream) {
+			return nil, ErrorCorruptedData
+		}
+	}
+
+	if len(src)-pos < 2 {
+		return nil, ErrorCorruptedData
+	}
+
+	off16Count := int(binary.LittleEndian.Uint16(src[pos:]))
+	pos += 2
+	if off16Count == 0xffff {
+		// entropy coded high and low bytes
+		var off16Hi, off16Lo []byte
+		off16Hi, n, err = decodeBytes(src[pos:], nil, dstSize>>1, false)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+
+		off16Lo, n, err = decodeBytes(src[pos:], nil, dstSize>>1, false)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+
+		if len(off16Lo) != len(off16Hi) {
+			return nil, ErrorCorruptedData
+		}
+
+		lz.off16Stream = make([]uint16, len(off16Lo))
+		for i := range lz.off16Stream {
+			lz.off16Stream[i] = uint16(off16Lo[i]) | uint16(off16Hi[i])<<8
+		}
+	} else {
+		if len(src)-pos < off16Count*2 {
+			return nil, ErrorCorruptedData
+		}
+
+		lz.off16Stream = make([]uint16, off16Count)
+		for i := range lz.off16Stream {
+			lz.off16Stream[i] = binary.LittleEndian.Uint16(src[pos+i*2:])
+		}
+		pos += off16Count * 2
+	}
+
+	if len(src)-pos < 3 {
+		return nil, ErrorCorruptedData
+	}
+
+	v := int(src[pos]) | int(src[pos+1])<<8 | int(src[pos+2])<<16
+	pos += 3
+	if v != 0 {
+		off32Size1 := v >> 12
+		off32Size2 := v & 0xfff
+		if off32Size1 == 4095 {
+			if len(src)-pos < 2 {
+				return nil, ErrorCorruptedData
+			}
+			off32Size1 = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		}
+		if off32Size2 == 4095 {
+			if len(src)-pos < 2 {
+				return nil, ErrorCorruptedData
+			}
+			off32Size2 = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		}
+
+		lz.off32Stream1, n, err = decodeFarOffsets(src[pos:], off32Size1, offset)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+
+		lz.off32Stream2, n, err = decodeFarOffsets(src[pos:], off32Size2, offset+0x10000)
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+	}
+
+	lz.lengthStream = src[pos:]
+
+	return lz, nil
+}
+
+// decodeFarOffsets reads count offsets relative to the block at offset.
+func decodeFarOffsets(src []byte, count int, offset int) ([]uint32, int, error) {
+	offsets := make([]uint32, count)
+
+	pos := 0
+	for i := range offsets {
+		if len(src)-pos < 3 {
+			return nil, 0, ErrorCorruptedData
+		}
+		off := uint32(src[pos]) | uint32(src[pos+1])<<8 | uint32(src[pos+2])<<16
+		pos += 3
+
+		if offset >= 0xc00000-1 && off >= 0xc00000 {
+			if pos == len(src) {
+				return nil, 0, ErrorCorruptedData
+			}
+			off += uint32(src[pos]) << 22
+			pos++
+		}
+
+		if int64(off) > int64(offset) {
+			return nil, 0, ErrorCorruptedData
+		}
+		offsets[i] = off
+	}
+
+	return offsets, pos, nil
+}
+
+// process executes the commands of a chunk as two blocks of 64 KiB. Mode 0 adds literals to the bytes at the last
+// offset, mode 1 copies them as is.
+func (lz *mermaidLzTable) process(mode int, dst []byte, offset int, dstSize int) error {
+	savedDist := -8
+	pos := offset
+	for iteration := 0; iteration < 2 && dstSize > 0; iteration++ {
+		blockSize := min(dstSize, 0x10000)
+
+		cmdStream := lz.cmdStream[:lz.cmdStream2Pos]
+		off32Stream := lz.off32Stream1
+		if iteration == 1 {
+			cmdStream = lz.cmdStream[lz.cmdStream2Pos:]
+			off32Stream = lz.off32Stream2
+		}
+
+		startOffset := 0
+		if offset == 0 && iteration == 0 {
+			startOffset = 8
+		}
+
+		err := lz.processBlock(dst, pos, blockSize, cmdStream, off32Stream, mode == 0, &savedDist, startOffset)
+		if err != nil {
+			return err
+		}
+
+		pos += blockSize
+		dstSize -= blockSize
+	}
+
+	if len(lz.lengthStream) != 0 {
+		return ErrorCorruptedData
+	}
+
+	return nil
+}
+
+func (lz *mermaidLzTable) processBlock(dst []byte, blockStart int, blockSize int, cmdStream []byte, off32Stream []uint32, delta bool, savedDist *int, startOffset int) error {
+	end := blockStart + blockSize
+	pos := blockStart + startOffset
+	recentOffs := *savedDist
+
+	for _, cmd := range cmdStream {
+		switch {
+		case cmd >= 24:
+			litLen := int(cmd & 7)
+			if litLen > len(lz.litStream) {
+				return ErrorCorruptedData
+			}
+			err := copyLiterals(dst, pos, lz.litStream[:litLen], recentOffs, delta)
+			if err != nil {
+				return err
+			}
+			pos += litLen
+			lz.litStream = lz.litStream[litLen:]
+
+			if cmd&0x80 == 0 {
+				if len(lz.off16Stream) == 0 {
+					return ErrorCorruptedData
+				}
+				recentOffs = -int(lz.off16Stream[0])
+				lz.off16Stream = lz.off16Stream[1:]
+			}
+
+			matchLen := int(cmd>>3) & 0xf
+			err = copyMatch(dst, pos, recentOffs, matchLen)
+			if err != nil {
+				return err
+			}
+			pos += matchLen
+
+		case cmd > 2:
+			length := int(cmd) + 5
+			if len(off32Stream) == 0 {
+				return ErrorCorruptedData
+			}
+			recentOffs = blockStart - int(off32Stream[0]) - pos
+			off32Stream = off32Stream[1:]
+
+			if end-pos < length {
+				return ErrorCorruptedData
+			}
+			err := copyMatch(dst, pos, recentOffs, length)
+			if err != nil {
+				return err
+			}
+			pos += length
+
+		case cmd == 0:
+			length, err := lz.readLength()
+			if err != nil {
+				return err
+			}
+			length += 64
+
+			if end-pos < length || len(lz.litStream) < length {
+				return ErrorCorruptedData
+			}
+			err = copyLiterals(dst, pos, lz.litStream[:length], recentOffs, delta)
+			if err != nil {
+				return err
+			}
+			pos += length
+			lz.litStream = lz.litStream[length:]
+
+		case cmd == 1:
+			length, err := lz.readLength()
+			if err != nil {
+				return err
+			}
+			length += 91
+
+			if len(lz.off16Stream) == 0 {
+				return ErrorCorruptedData
+			}
+			recentOffs = -int(lz.off16Stream[0])
+			lz.off16Stream = lz.off16Stream[1:]
+
+			err = copyMatch(dst, pos, recentOffs, length)
+			if err != nil {
+				return err
+			}
+			pos += length
+
+		default:
+			length, err := lz.readLength()
+			if err != nil {
+				return err
+			}
+			length += 29
+
+			if len(off32Stream) == 0 {
+				return ErrorCorruptedData
+			}
+			recentOffs = blockStart - int(off32Stream[0]) - pos
+			off32Stream = off32Stream[1:]
+
+			err = copyMatch(dst, pos, recentOffs, length)
+			if err != nil {
+				return err
+			}
+			pos += length
+		}
+	}
+
+	if end > pos {
+		length := end - pos
+		if len(lz.litStream) < length {
+			return ErrorCorruptedData
+		}
+		err := copyLiterals(dst, pos, lz.litStream[:length], recentOffs, delta)
+		if err != nil {
+			return err
+		}
+		lz.litStream = lz.litStream[length:]
+	}
+
+	*savedDist = recentOffs
+
+	return nil
+}
+
+func (lz *mermaidLzTable) readLength() (int, error) {
+	if len(lz.lengthStream) == 0 {
+		return 0, ErrorCorruptedData
+	}
+
+	length := int(lz.lengthStream[0])
+	if length > 251 {
+		if len(lz.lengthStream) < 3 {
+			return 0, ErrorCorruptedData
+		}
+		length += int(binary.LittleEndian.Uint16(lz.lengthStream[1:])) * 4
+		lz.lengthStream = lz.lengthStream[2:]
+	}
+	lz.lengthStream = lz.lengthStream[1:]
+
+	return length, nil
+}
@@ -0,0 +1,202 @@
+// Package oodle decodes Oodle compressed data. Kraken, Mermaid and Selkie streams are decoded natively, the oo2core
+// library is used instead when it is available on Windows.
+package oodle
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrorCorruptedData = errors.New("corrupted oodle data")
+
+const (
+	decoderLzna      = 5
+	decoderKraken    = 6
+	decoderMermaid   = 10
+	decoderBitknit   = 11
+	decoderLeviathan = 12
+)
+
+var decoderNames = map[int]string{
+	decoderLzna:      "LZNA",
+	decoderKraken:    "Kraken",
+	decoderMermaid:   "Mermaid",
+	decoderBitknit:   "BitKnit",
+	decoderLeviathan: "Leviathan",
+}
+
+type header struct {
+	decoderType  int
+	uncompressed bool
+	useChecksums bool
+}
+
+type quantumHeader struct {
+	compressedSize int
+	checksum       uint32
+}
+
+// Decompress decodes data into a buffer of uncompressedSize bytes.
+func Decompress(data []byte, uncompressedSize int64) ([]byte, error) {
+	output, ok, err := decompressDll(data, uncompressedSize)
+	if ok {
+		return output, err
+	}
+
+	return DecompressNative(data, uncompressedSize)
+}
+
+// DecompressNative is Decompress without the oo2core library.
+func DecompressNative(data []byte, uncompressedSize int64) ([]byte, error) {
+	if uncompressedSize < 0 || uncompressedSize > math.MaxInt32 {
+		return nil, fmt.Errorf("not valid uncompressedSize: %d", uncompressedSize)
+	}
+
+	dst := make([]byte, uncompressedSize)
+	hdr := &header{}
+
+	pos := 0
+	offset := 0
+	for offset < len(dst) {
+		srcUsed, dstUsed, err := decodeStep(hdr, dst, offset, data[pos:])
+		if err != nil {
+			return nil, err
+		}
+
+		pos += srcUsed
+		offset += dstUsed
+	}
+
+	return dst, nil
+}
+
+// decodeStep decodes one quantum of up to 256 KiB and returns the number of bytes read and written.
+func decodeStep(hdr *header, dst []byte, offset int, src []byte) (int, int, error) {
+	pos := 0
+	if offset&0x3ffff == 0 {
+		n, err := parseHeader(hdr, src)
+		if err != nil {
+			return 0, 0, err
+		}
+		pos += n
+	}
+
+	if hdr.decoderType != decoderKraken && hdr.decoderType != decoderMermaid {
+		return 0, 0, fmt.Errorf("unsupported oodle decoder: %s", decoderNames[hdr.decoderType])
+	}
+
+	dstCount := min(len(dst)-offset, 0x40000)
+
+	if hdr.uncompressed {
+		if len(src)-pos < dstCount {
+			return 0, 0, ErrorCorruptedData
+		}
+		copy(dst[offset:], src[pos:pos+dstCount])
+
+		return pos + dstCount, dstCount, nil
+	}
+
+	qhdr, n, err := parseQuantumHeader(src[pos:], hdr.useChecksums)
+	if err != nil {
+		return 0, 0, err
+	}
+	pos += n
+
+	if len(src)-pos < qhdr.compressedSize || qhdr.compressedSize > dstCount {
+		return 0, 0, ErrorCorruptedData
+	}
+
+	if qhdr.compressedSize == 0 {
+		for i := offset; i < offset+dstCount; i++ {
+			dst[i] = byte(qhdr.checksum)
+		}
+
+		return pos, dstCount, nil
+	}
+
+	// checksums are not verified
+	body := src[pos : pos+qhdr.compressedSize]
+
+	if qhdr.compressedSize == dstCount {
+		copy(dst[offset:], body)
+
+		return pos + dstCount, dstCount, nil
+	}
+
+	switch hdr.decoderType {
+	case decoderKraken:
+		n, err = decodeKrakenQuantum(dst, offset, offset+dstCount, body)
+	case decoderMermaid:
+		n, err = decodeMermaidQuantum(dst, offset, offset+dstCount, body)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if n != qhdr.compressedSize {
+		return 0, 0, ErrorCorruptedData
+	}
+
+	return pos + n, dstCount, nil
+}
+
+func parseHeader(hdr *header, src []byte) (int, error) {
+	if len(src) < 2 {
+		return 0, ErrorCorruptedData
+	}
+
+	b := src[0]
+	if b&0xf != 0xc || (b>>4)&3 != 0 {
+		return 0, ErrorCorruptedData
+	}
+	hdr.uncompressed = (b>>6)&1 != 0
+
+	b = src[1]
+	hdr.decoderType = int(b & 0x7f)
+	hdr.useChecksums = b>>7 != 0
+
+	_, ok := decoderNames[hdr.decoderType]
+	if !ok {
+		return 0, fmt.Errorf("unknown oodle decoder: %d", hdr.decoderType)
+	}
+
+	return 2, nil
+}
+
+func parseQuantumHeader(src []byte, useChecksums bool) (*quantumHeader, int, error) {
+	if len(src) < 3 {
+		return nil, 0, ErrorCorruptedData
+	}
+
+	v := uint32(src[0])<<16 | uint32(src[1])<<8 | uint32(src[2])
+	size := v & 0x3ffff
+	if size != 0x3ffff {
+		qhdr := &quantumHeader{
+			compressedSize: int(size) + 1,
+		}
+		if !useChecksums {
+			return qhdr, 3, nil
+		}
+
+		if len(src) < 6 {
+			return nil, 0, ErrorCorruptedData
+		}
+		qhdr.checksum = uint32(src[3])<<16 | uint32(src[4])<<8 | uint32(src[5])
+
+		return qhdr, 6, nil
+	}
+
+	if v>>18 == 1 {
+		// the whole quantum is a single byte
+		if len(src) < 4 {
+			return nil, 0, ErrorCorruptedData
+		}
+
+		return &quantumHeader{
+			checksum: uint32(src[3]),
+		}, 4, nil
+	}
+
+	return nil, 0, ErrorCorruptedData
+}
@@ -0,0 +1,83 @@
+package oodle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// decodeVectors are hand-assembled streams: 8 raw bytes, stored literal and command blocks and matches at the
+// initial recent offset of -8 or a 16-bit offset, so the overlapping copies are covered.
+var decodeVectors = []struct {
+	name string
+	data string
+	want []byte
+}{
+	{
+		name: "Kraken raw literals",
+		data: "8c0600001e88001c616263646566676800000558595a21210000021a3900000000000080",
+		want: []byte("abcdefghXYcdefghXYZdefghXYZdefghXYZ!!"),
+	},
+	{
+		name: "Kraken delta literals",
+		data: "8c0600001e80001c616263646566676800000558595a21210000021a3900000000000080",
+		want: mustDecodeHex("6162636465666768b9bb636465666768b9bbbd6465666768b9bbbd6465666768b9bbbd8586"),
+	},
+	{
+		name: "Mermaid raw literals",
+		data: "8c0a00001e88001c616263646566676800000558595a2121000002c27901000300000000",
+		want: []byte("abcdefghXYcdefghXYZXYZXYZXYZXYZXYZ!!"),
+	},
+	{
+		name: "Kraken memset and stored quanta",
+		data: "8c0607ffff71" + "8c06000002" + "616263",
+		want: append(bytes.Repeat([]byte("q"), 0x40000), "abc"...),
+	},
+	{
+		name: "uncompressed",
+		data: "cc06" + "616263",
+		want: []byte("abc"),
+	},
+}
+
+func mustDecodeHex(s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+func TestDecompressNative(t *testing.T) {
+	for _, vector := range decodeVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			got, err := DecompressNative(mustDecodeHex(vector.data), int64(len(vector.want)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, vector.want) {
+				t.Fatalf("got %q, want %q", got, vector.want)
+			}
+		})
+	}
+}
+
+func TestDecompressNativeCorrupted(t *testing.T) {
+	for _, vector := range decodeVectors {
+		data := mustDecodeHex(vector.data)
+		for i := range data {
+			_, err := DecompressNative(data[:i], int64(len(vector.want)))
+			if err == nil {
+				t.Fatalf("%s: no error for %d of %d bytes", vector.name, i, len(data))
+			}
+		}
+	}
+
+	// the split of the huffman streams points past the block
+	_, err := DecompressNative([]byte("\x8c\x060\x00\x10 \x000\x00\a\x00\x800000000000"), 18)
+	if err == nil {
+		t.Fatal("no error for a corrupted huffman block")
+	}
+}
@@ -4,15 +4,45 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
 )
 
+// chunkSize bounds how much ReadBytes allocates ahead of the data actually read, so a size taken from a corrupt file
+// fails with io.ErrUnexpectedEOF instead of allocating it.
+const chunkSize = 1 << 20
+
 func ReadBytes(r io.Reader, size int) ([]byte, error) {
-	buf := make([]byte, size)
+	if size < 0 {
+		return nil, fmt.Errorf("negative size: %d", size)
+	}
 
-	_, err := io.ReadFull(r, buf)
-	if err != nil {
-		return nil, err
+	if size <= chunkSize {
+		buf := make([]byte, size)
+
+		_, err := io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf, nil
+	}
+
+	buf := make([]byte, 0, chunkSize)
+	for len(buf) < size {
+		n := min(size-len(buf), chunkSize)
+		buf = slices.Grow(buf, n)
+
+		_, err := io.ReadFull(r, buf[len(buf):len(buf)+n])
+		if err == io.EOF && len(buf) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		buf = buf[:len(buf)+n]
 	}
 
 	return buf, nil
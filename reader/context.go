@@ -0,0 +1,29 @@
+package reader
+
+import (
+	"context"
+	"io"
+)
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewContextReader returns a reader that fails with the error of ctx once ctx is done, a long parse or copy stops at
+// the next Read.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{
+		ctx: ctx,
+		r:   r,
+	}
+}
+
+func (reader *contextReader) Read(p []byte) (int, error) {
+	err := reader.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return reader.r.Read(p)
+}
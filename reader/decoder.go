@@ -0,0 +1,168 @@
+package reader
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FormatError is an error of a parser with the field it was reading and the offset of that field in the file.
+type FormatError struct {
+	Format string
+	Field  string
+	Offset int64
+	Err    error
+}
+
+func (err *FormatError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d (0x%x): %s", err.Format, err.Field, err.Offset, err.Offset, err.Err)
+}
+
+func (err *FormatError) Unwrap() error {
+	return err.Err
+}
+
+// Decoder reads the fields of a file of format and counts the bytes read. Its errors are *FormatError.
+type Decoder struct {
+	r      io.Reader
+	format string
+	offset int64
+	// fieldOffset is the offset of the last field read
+	fieldOffset int64
+}
+
+func NewDecoder(r io.Reader, format string) *Decoder {
+	return &Decoder{
+		r:      r,
+		format: format,
+	}
+}
+
+// NewDecoderAt returns a Decoder of the part of a file read by r, offset is the offset of that part in the file.
+func NewDecoderAt(r io.Reader, format string, offset int64) *Decoder {
+	return &Decoder{
+		r:           r,
+		format:      format,
+		offset:      offset,
+		fieldOffset: offset,
+	}
+}
+
+// Read reads from the underlying reader and counts the bytes, it lets blocks be decoded from the Decoder.
+func (decoder *Decoder) Read(p []byte) (int, error) {
+	n, err := decoder.r.Read(p)
+	decoder.offset += int64(n)
+
+	return n, err
+}
+
+// Offset returns the number of bytes read so far.
+func (decoder *Decoder) Offset() int64 {
+	return decoder.offset
+}
+
+// Error returns err as a *FormatError of field at the offset of the last field read, it is meant for the checks done
+// after reading a field. A *FormatError is returned as it is.
+func (decoder *Decoder) Error(field string, err error) error {
+	return decoder.ErrorAt(field, decoder.fieldOffset, err)
+}
+
+func (decoder *Decoder) ErrorAt(field string, offset int64, err error) error {
+	var formatError *FormatError
+	if errors.As(err, &formatError) {
+		return err
+	}
+
+	return &FormatError{
+		Format: decoder.format,
+		Field:  field,
+		Offset: offset,
+		Err:    err,
+	}
+}
+
+func (decoder *Decoder) ReadBytes(field string, size int) ([]byte, error) {
+	decoder.fieldOffset = decoder.offset
+
+	data, err := ReadBytes(decoder, size)
+	if err != nil {
+		return nil, decoder.Error(field, err)
+	}
+
+	return data, nil
+}
+
+func (decoder *Decoder) ReadUint8(field string) (uint8, error) {
+	decoder.fieldOffset = decoder.offset
+
+	value, err := ReadUint8(decoder)
+	if err != nil {
+		return 0, decoder.Error(field, err)
+	}
+
+	return value, nil
+}
+
+func (decoder *Decoder) ReadUint16(field string, byteOrder binary.ByteOrder) (uint16, error) {
+	decoder.fieldOffset = decoder.offset
+
+	value, err := ReadUint16(decoder, byteOrder)
+	if err != nil {
+		return 0, decoder.Error(field, err)
+	}
+
+	return value, nil
+}
+
+func (decoder *Decoder) ReadUint32(field string, byteOrder binary.ByteOrder) (uint32, error) {
+	decoder.fieldOffset = decoder.offset
+
+	value, err := ReadUint32(decoder, byteOrder)
+	if err != nil {
+		return 0, decoder.Error(field, err)
+	}
+
+	return value, nil
+}
+
+func (decoder *Decoder) ReadUint64(field string, byteOrder binary.ByteOrder) (uint64, error) {
+	decoder.fieldOffset = decoder.offset
+
+	value, err := ReadUint64(decoder, byteOrder)
+	if err != nil {
+		return 0, decoder.Error(field, err)
+	}
+
+	return value, nil
+}
+
+// ReadNullTerminatedString returns io.EOF as it is when there is no data left.
+func (decoder *Decoder) ReadNullTerminatedString(field string) (string, error) {
+	decoder.fieldOffset = decoder.offset
+
+	value, err := ReadNullTerminatedString(decoder)
+	if err == io.EOF {
+		return "", err
+	}
+	if err != nil {
+		return "", decoder.Error(field, err)
+	}
+
+	return value, nil
+}
+
+// Skip discards size bytes.
+func (decoder *Decoder) Skip(field string, size int64) error {
+	decoder.fieldOffset = decoder.offset
+
+	n, err := io.CopyN(io.Discard, decoder, size)
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return decoder.Error(field, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecoderError(t *testing.T) {
+	inner := &FormatError{Format: "inner", Field: "Inner", Offset: 1, Err: io.ErrUnexpectedEOF}
+	checkErr := errors.New("wrong value")
+
+	tests := []struct {
+		name       string
+		data       []byte
+		offset     int64
+		read       func(d *Decoder) error
+		wantFormat string
+		wantField  string
+		wantOffset int64
+		wantErr    error
+	}{
+		{
+			name: "short field",
+			data: []byte{0x01, 0x00, 0x02},
+			read: func(d *Decoder) error {
+				_, err := d.ReadUint16("A", binary.LittleEndian)
+				if err != nil {
+					return err
+				}
+				_, err = d.ReadUint32("B", binary.LittleEndian)
+				return err
+			},
+			wantFormat: "test",
+			wantField:  "B",
+			wantOffset: 2,
+			wantErr:    io.ErrUnexpectedEOF,
+		},
+		{
+			name: "check after a field",
+			data: []byte{0x01, 0x00, 0x02, 0x00},
+			read: func(d *Decoder) error {
+				_, err := d.ReadUint16("A", binary.LittleEndian)
+				if err != nil {
+					return err
+				}
+				_, err = d.ReadUint16("B", binary.LittleEndian)
+				if err != nil {
+					return err
+				}
+				return d.Error("B", checkErr)
+			},
+			wantFormat: "test",
+			wantField:  "B",
+			wantOffset: 2,
+			wantErr:    checkErr,
+		},
+		{
+			name:   "part of a file",
+			data:   []byte{0x01},
+			offset: 100,
+			read: func(d *Decoder) error {
+				_, err := d.ReadUint16("A", binary.LittleEndian)
+				return err
+			},
+			wantFormat: "test",
+			wantField:  "A",
+			wantOffset: 100,
+			wantErr:    io.ErrUnexpectedEOF,
+		},
+		{
+			name: "nested",
+			read: func(d *Decoder) error {
+				return d.ErrorAt("Outer", 10, inner)
+			},
+			wantFormat: "inner",
+			wantField:  "Inner",
+			wantOffset: 1,
+			wantErr:    io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDecoderAt(bytes.NewReader(test.data), "test", test.offset)
+
+			err := test.read(d)
+
+			var formatError *FormatError
+			if !errors.As(err, &formatError) {
+				t.Fatalf("got %v, want a *FormatError", err)
+			}
+			if formatError.Format != test.wantFormat || formatError.Field != test.wantField || formatError.Offset != test.wantOffset {
+				t.Fatalf("got %s %s at %d, want %s %s at %d", formatError.Format, formatError.Field, formatError.Offset, test.wantFormat, test.wantField, test.wantOffset)
+			}
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatErrorString(t *testing.T) {
+	err := &FormatError{Format: "mnf", Field: "Version", Offset: 26, Err: io.ErrUnexpectedEOF}
+
+	want := "mnf: Version at offset 26 (0x1a): unexpected EOF"
+	if err.Error() != want {
+		t.Fatalf("got %q, want %q", err.Error(), want)
+	}
+}
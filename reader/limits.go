@@ -0,0 +1,57 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var ErrorLimitExceeded = errors.New("limit exceeded")
+
+// Limits bound the sizes and counts the parsers accept from a file, a corrupt file fails with ErrorLimitExceeded
+// instead of allocating whatever it claims.
+type Limits struct {
+	// MaxSize is the largest block, payload or string in bytes
+	MaxSize uint64
+	// MaxCount is the largest number of records
+	MaxCount uint64
+}
+
+// DefaultLimits are used by the parsers that are not given limits.
+var DefaultLimits = Limits{
+	MaxSize:  1 << 30,
+	MaxCount: 1 << 24,
+}
+
+func (limits Limits) CheckSize(size uint64) error {
+	if size > limits.MaxSize {
+		return fmt.Errorf("%w: size %d is larger than %d", ErrorLimitExceeded, size, limits.MaxSize)
+	}
+
+	return nil
+}
+
+func (limits Limits) CheckCount(count uint64) error {
+	if count > limits.MaxCount {
+		return fmt.Errorf("%w: count %d is larger than %d", ErrorLimitExceeded, count, limits.MaxCount)
+	}
+
+	return nil
+}
+
+// ReadAll reads r until EOF, it fails after MaxSize bytes.
+func (limits Limits) ReadAll(r io.Reader) ([]byte, error) {
+	maxSize := int64(min(limits.MaxSize, math.MaxInt64-1))
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: data is larger than %d", ErrorLimitExceeded, limits.MaxSize)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package zosft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/eso-tools/eso-tools/reader"
+	"testing"
+)
+
+var fuzzLimits = reader.Limits{
+	MaxSize:  1 << 20,
+	MaxCount: 1 << 16,
+}
+
+func FuzzParse(f *testing.F) {
+	names := []byte("art/icon.dds\x00art/other.dds\x00")
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(signature)
+	buf.Write(make([]byte, 10))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	for i := 0; i < 3; i++ {
+		binary.Write(buf, binary.LittleEndian, uint16(i))
+		binary.Write(buf, binary.LittleEndian, []uint32{0, 0, 0, 0})
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(len(names)))
+	buf.Write(names)
+	buf.WriteString(signature)
+
+	f.Add(buf.Bytes())
+	f.Add([]byte(signature))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		zosftData, err := ParseWithLimits(bytes.NewReader(data), fuzzLimits)
+		if err != nil {
+			return
+		}
+
+		zosftData.GetFileNamesById()
+	})
+}
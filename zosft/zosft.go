@@ -5,6 +5,7 @@ import (
 	"compress/zlib"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/eso-tools/eso-tools/reader"
 	"io"
 )
@@ -23,6 +24,13 @@ type Zosft struct {
 	DataSize uint32
 
 	OffsetFileName map[uint32]string
+
+	// Warnings report the blocks whose size does not match their records
+	Warnings []string
+}
+
+func (zosftData *Zosft) addWarning(format string, args ...any) {
+	zosftData.Warnings = append(zosftData.Warnings, fmt.Sprintf(format, args...))
 }
 
 func (zosftData *Zosft) GetFileNamesById() map[uint32]string {
@@ -98,7 +106,13 @@ type Index3 struct {
 	Block3Records          []*Index3Block3Record
 }
 
+// Parse parses a ZOSFT table with reader.DefaultLimits.
 func Parse(r io.Reader) (*Zosft, error) {
+	return ParseWithLimits(r, reader.DefaultLimits)
+}
+
+// ParseWithLimits parses a ZOSFT table, the counts and sizes of its blocks are bounded by limits.
+func ParseWithLimits(r io.Reader, limits reader.Limits) (*Zosft, error) {
 	var data []byte
 	var err error
 
@@ -106,54 +120,60 @@ func Parse(r io.Reader) (*Zosft, error) {
 		OffsetFileName: map[uint32]string{},
 	}
 
-	data, err = reader.ReadBytes(r, len([]byte(signature)))
+	d := reader.NewDecoder(r, "zosft")
+
+	data, err = d.ReadBytes("Signature", len([]byte(signature)))
 	if err != nil {
 		return nil, err
 	}
 
 	if string(data) != signature {
-		return nil, errors.New("wrong signature")
+		return nil, d.Error("Signature", errors.New("wrong signature"))
 	}
 	zosftData.Signature = signature
 
-	data, err = reader.ReadBytes(r, 10)
+	data, err = d.ReadBytes("Field2", 10)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.Field2 = data
 
-	count, err := reader.ReadUint32(r, binary.LittleEndian)
+	count, err := d.ReadUint32("Count", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.Count = count
 
 	// indexes
-	index1Data, err := parseIndex1(r)
+	index1Data, err := parseIndex1(d, limits, zosftData.addWarning)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.Index1 = index1Data
 
-	index2Data, err := parseIndex2(r)
+	index2Data, err := parseIndex2(d, limits, zosftData.addWarning)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.Index2 = index2Data
 
-	index3Data, err := parseIndex3(r)
+	if int64(zosftData.Count) > int64(len(index2Data.Block2Records)) || int64(zosftData.Count) > int64(len(index2Data.Block3Records)) {
+		return nil, d.Error("Count", fmt.Errorf("count %d does not match index 2 records %d and %d", zosftData.Count, len(index2Data.Block2Records), len(index2Data.Block3Records)))
+	}
+
+	index3Data, err := parseIndex3(d, limits, zosftData.addWarning)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.Index3 = index3Data
 
-	dataSize, err := reader.ReadUint32(r, binary.LittleEndian)
+	dataSize, err := d.ReadUint32("DataSize", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	zosftData.DataSize = dataSize
 
-	lr := io.LimitReader(r, int64(zosftData.DataSize))
+	lr := io.LimitReader(d, int64(zosftData.DataSize))
 
 	fileNameBuf := bytes.NewBuffer(nil)
 	var offset uint32
@@ -164,7 +184,7 @@ func Parse(r io.Reader) (*Zosft, error) {
 			if err == io.EOF {
 				break
 			}
-			return nil, err
+			return nil, d.Error("FileNames", err)
 		}
 		b := data[0]
 
@@ -179,13 +199,13 @@ func Parse(r io.Reader) (*Zosft, error) {
 		i++
 	}
 
-	data, err = reader.ReadBytes(r, len([]byte(signature)))
+	data, err = d.ReadBytes("EndSignature", len([]byte(signature)))
 	if err != nil {
 		return nil, err
 	}
 
 	if string(data) != signature {
-		return nil, errors.New("wrong signature")
+		return nil, d.Error("EndSignature", errors.New("wrong signature"))
 	}
 
 	return zosftData, nil
@@ -204,137 +224,167 @@ type Index1Block3Record struct {
 	Id uint32
 }
 
-func parseIndex1(r io.Reader) (*Index1, error) {
+func parseIndex1(d *reader.Decoder, limits reader.Limits, warn func(format string, args ...any)) (*Index1, error) {
 	indexData := &Index1{}
 
-	id, err := reader.ReadUint16(r, binary.LittleEndian)
+	id, err := d.ReadUint16("Index1.Id", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Id = id
 
-	field2, err := reader.ReadUint32(r, binary.LittleEndian)
+	field2, err := d.ReadUint32("Index1.Field2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Field2 = field2
 
-	count1, err := reader.ReadUint32(r, binary.LittleEndian)
+	count1, err := d.ReadUint32("Index1.Count1", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count1 = count1
 
-	count2, err := reader.ReadUint32(r, binary.LittleEndian)
+	count2, err := d.ReadUint32("Index1.Count2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count2 = count2
 
-	count3, err := reader.ReadUint32(r, binary.LittleEndian)
+	count3, err := d.ReadUint32("Index1.Count3", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count3 = count3
 
 	if indexData.Count1 > 0 {
-		uncompressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock1Size, err := d.ReadUint32("Index1.UncompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock1Size = uncompressedBlock1Size
 
-		compressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock1Size, err := d.ReadUint32("Index1.CompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock1Size = compressedBlock1Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock1Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock1Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index1.Block1Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block1Records = []*Index1Block1Record{}
-		recordSize := int(indexData.UncompressedBlock1Size / indexData.Count1)
+		recordSize, err := getRecordSize(d, "Index1.Block1Records", limits, indexData.Count1, indexData.UncompressedBlock1Size, 4, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count1; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index1.Block1Records", offset, err)
 			}
 			indexData.Block1Records = append(indexData.Block1Records, &Index1Block1Record{
 				Index11: binary.LittleEndian.Uint32(recordData) & 0xffffff,
 				Flag:    recordData[3],
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index1.Block1Records", offset, err)
+		}
 	}
 
 	if indexData.Count2 > 0 {
-		uncompressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock2Size, err := d.ReadUint32("Index1.UncompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock2Size = uncompressedBlock2Size
 
-		compressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock2Size, err := d.ReadUint32("Index1.CompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock2Size = compressedBlock2Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock2Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock2Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index1.Block2Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block2Records = []*Index1Block2Record{}
-		recordSize := int(indexData.UncompressedBlock2Size / indexData.Count2)
+		recordSize, err := getRecordSize(d, "Index1.Block2Records", limits, indexData.Count2, indexData.UncompressedBlock2Size, 1, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count2; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index1.Block2Records", offset, err)
 			}
 			indexData.Block2Records = append(indexData.Block2Records, &Index1Block2Record{
 				Field1: recordData,
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index1.Block2Records", offset, err)
+		}
 	}
 
 	if indexData.Count3 > 0 {
-		uncompressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock3Size, err := d.ReadUint32("Index1.UncompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock3Size = uncompressedBlock3Size
 
-		compressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock3Size, err := d.ReadUint32("Index1.CompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock3Size = compressedBlock3Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock3Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock3Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index1.Block3Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block3Records = []*Index1Block3Record{}
-		recordSize := int(indexData.UncompressedBlock3Size / indexData.Count3)
+		recordSize, err := getRecordSize(d, "Index1.Block3Records", limits, indexData.Count3, indexData.UncompressedBlock3Size, 4, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count3; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index1.Block3Records", offset, err)
 			}
 			indexData.Block3Records = append(indexData.Block3Records, &Index1Block3Record{
 				Id: binary.LittleEndian.Uint32(recordData),
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index1.Block3Records", offset, err)
+		}
 	}
 
 	return indexData, nil
@@ -355,132 +405,156 @@ type Index2Block3Record struct {
 	Field3 []byte
 }
 
-func parseIndex2(r io.Reader) (*Index2, error) {
+func parseIndex2(d *reader.Decoder, limits reader.Limits, warn func(format string, args ...any)) (*Index2, error) {
 	indexData := &Index2{}
 
-	id, err := reader.ReadUint16(r, binary.LittleEndian)
+	id, err := d.ReadUint16("Index2.Id", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Id = id
 
-	field2, err := reader.ReadUint32(r, binary.LittleEndian)
+	field2, err := d.ReadUint32("Index2.Field2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Field2 = field2
 
-	count1, err := reader.ReadUint32(r, binary.LittleEndian)
+	count1, err := d.ReadUint32("Index2.Count1", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count1 = count1
 
-	count2, err := reader.ReadUint32(r, binary.LittleEndian)
+	count2, err := d.ReadUint32("Index2.Count2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count2 = count2
 
-	count3, err := reader.ReadUint32(r, binary.LittleEndian)
+	count3, err := d.ReadUint32("Index2.Count3", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count3 = count3
 
 	if indexData.Count1 > 0 {
-		uncompressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock1Size, err := d.ReadUint32("Index2.UncompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock1Size = uncompressedBlock1Size
 
-		compressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock1Size, err := d.ReadUint32("Index2.CompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock1Size = compressedBlock1Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock1Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock1Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index2.Block1Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block1Records = []*Index2Block1Record{}
-		recordSize := int(indexData.UncompressedBlock1Size / indexData.Count1)
+		recordSize, err := getRecordSize(d, "Index2.Block1Records", limits, indexData.Count1, indexData.UncompressedBlock1Size, 4, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count1; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index2.Block1Records", offset, err)
 			}
 			indexData.Block1Records = append(indexData.Block1Records, &Index2Block1Record{
 				Index21: binary.LittleEndian.Uint32(recordData) & 0xffffff,
 				Flag:    recordData[3],
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index2.Block1Records", offset, err)
+		}
 	}
 
 	if indexData.Count2 > 0 {
-		uncompressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock2Size, err := d.ReadUint32("Index2.UncompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock2Size = uncompressedBlock2Size
 
-		compressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock2Size, err := d.ReadUint32("Index2.CompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock2Size = compressedBlock2Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock2Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock2Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index2.Block2Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block2Records = []*Index2Block2Record{}
-		recordSize := int(indexData.UncompressedBlock2Size / indexData.Count2)
+		recordSize, err := getRecordSize(d, "Index2.Block2Records", limits, indexData.Count2, indexData.UncompressedBlock2Size, 4, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count2; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index2.Block2Records", offset, err)
 			}
 			indexData.Block2Records = append(indexData.Block2Records, &Index2Block2Record{
 				Id: binary.LittleEndian.Uint32(recordData),
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index2.Block2Records", offset, err)
+		}
 	}
 
 	if indexData.Count3 > 0 {
-		uncompressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock3Size, err := d.ReadUint32("Index2.UncompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock3Size = uncompressedBlock3Size
 
-		compressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock3Size, err := d.ReadUint32("Index2.CompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock3Size = compressedBlock3Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock3Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock3Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index2.Block3Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block3Records = []*Index2Block3Record{}
-		recordSize := int(indexData.UncompressedBlock3Size / indexData.Count3)
+		recordSize, err := getRecordSize(d, "Index2.Block3Records", limits, indexData.Count3, indexData.UncompressedBlock3Size, 16, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count3; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index2.Block3Records", offset, err)
 			}
 			indexData.Block3Records = append(indexData.Block3Records, &Index2Block3Record{
 				Id:     binary.LittleEndian.Uint32(recordData[0:4]),
@@ -488,6 +562,12 @@ func parseIndex2(r io.Reader) (*Index2, error) {
 				Field3: recordData[8:16],
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index2.Block3Records", offset, err)
+		}
 	}
 
 	return indexData, nil
@@ -506,138 +586,193 @@ type Index3Block3Record struct {
 	Field1 []byte
 }
 
-func parseIndex3(r io.Reader) (*Index3, error) {
+func parseIndex3(d *reader.Decoder, limits reader.Limits, warn func(format string, args ...any)) (*Index3, error) {
 	indexData := &Index3{}
 
-	id, err := reader.ReadUint16(r, binary.LittleEndian)
+	id, err := d.ReadUint16("Index3.Id", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Id = id
 
-	field2, err := reader.ReadUint32(r, binary.LittleEndian)
+	field2, err := d.ReadUint32("Index3.Field2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Field2 = field2
 
-	count1, err := reader.ReadUint32(r, binary.LittleEndian)
+	count1, err := d.ReadUint32("Index3.Count1", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count1 = count1
 
-	count2, err := reader.ReadUint32(r, binary.LittleEndian)
+	count2, err := d.ReadUint32("Index3.Count2", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count2 = count2
 
-	count3, err := reader.ReadUint32(r, binary.LittleEndian)
+	count3, err := d.ReadUint32("Index3.Count3", binary.LittleEndian)
 	if err != nil {
 		return nil, err
 	}
 	indexData.Count3 = count3
 
 	if indexData.Count1 > 0 {
-		uncompressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock1Size, err := d.ReadUint32("Index3.UncompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock1Size = uncompressedBlock1Size
 
-		compressedBlock1Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock1Size, err := d.ReadUint32("Index3.CompressedBlock1Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock1Size = compressedBlock1Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock1Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock1Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index3.Block1Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block1Records = []*Index3Block1Record{}
-		recordSize := int(indexData.UncompressedBlock1Size / indexData.Count1)
+		recordSize, err := getRecordSize(d, "Index3.Block1Records", limits, indexData.Count1, indexData.UncompressedBlock1Size, 4, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count1; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index3.Block1Records", offset, err)
 			}
 			indexData.Block1Records = append(indexData.Block1Records, &Index3Block1Record{
 				Field1: binary.LittleEndian.Uint32(recordData) & 0xffffff,
 				Flag:   recordData[3],
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index3.Block1Records", offset, err)
+		}
 	}
 
 	if indexData.Count2 > 0 {
-		uncompressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock2Size, err := d.ReadUint32("Index3.UncompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock2Size = uncompressedBlock2Size
 
-		compressedBlock2Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock2Size, err := d.ReadUint32("Index3.CompressedBlock2Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock2Size = compressedBlock2Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock2Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock2Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index3.Block2Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block2Records = []*Index3Block2Record{}
-		recordSize := int(indexData.UncompressedBlock2Size / indexData.Count2)
+		recordSize, err := getRecordSize(d, "Index3.Block2Records", limits, indexData.Count2, indexData.UncompressedBlock2Size, 1, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count2; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index3.Block2Records", offset, err)
 			}
 			indexData.Block2Records = append(indexData.Block2Records, &Index3Block2Record{
 				Field1: recordData,
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index3.Block2Records", offset, err)
+		}
 	}
 
 	if indexData.Count3 > 0 {
-		uncompressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		uncompressedBlock3Size, err := d.ReadUint32("Index3.UncompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.UncompressedBlock3Size = uncompressedBlock3Size
 
-		compressedBlock3Size, err := reader.ReadUint32(r, binary.LittleEndian)
+		compressedBlock3Size, err := d.ReadUint32("Index3.CompressedBlock3Size", binary.LittleEndian)
 		if err != nil {
 			return nil, err
 		}
 		indexData.CompressedBlock3Size = compressedBlock3Size
 
-		lr := io.LimitReader(r, int64(indexData.CompressedBlock3Size))
+		offset := d.Offset()
+		lr := io.LimitReader(d, int64(indexData.CompressedBlock3Size))
 		zr, err := zlib.NewReader(lr)
 		if err != nil {
-			return nil, err
+			return nil, d.ErrorAt("Index3.Block3Records", offset, err)
 		}
 		defer zr.Close()
 
 		indexData.Block3Records = []*Index3Block3Record{}
-		recordSize := int(indexData.UncompressedBlock3Size / indexData.Count3)
+		recordSize, err := getRecordSize(d, "Index3.Block3Records", limits, indexData.Count3, indexData.UncompressedBlock3Size, 1, warn)
+		if err != nil {
+			return nil, err
+		}
 		for i := uint32(0); i < indexData.Count3; i++ {
 			recordData, err := reader.ReadBytes(zr, recordSize)
 			if err != nil {
-				return nil, err
+				return nil, d.ErrorAt("Index3.Block3Records", offset, err)
 			}
 			indexData.Block3Records = append(indexData.Block3Records, &Index3Block3Record{
 				Field1: recordData,
 			})
 		}
+
+		// the rest of the block is not part of the next field
+		_, err = io.Copy(io.Discard, lr)
+		if err != nil {
+			return nil, d.ErrorAt("Index3.Block3Records", offset, err)
+		}
 	}
 
 	return indexData, nil
 }
+
+// getRecordSize returns the size of the count records of the block field of uncompressedSize. The records must be at
+// least minSize bytes, a block they do not fill is reported to warn and the rest of it is not read.
+func getRecordSize(d *reader.Decoder, field string, limits reader.Limits, count uint32, uncompressedSize uint32, minSize uint32, warn func(format string, args ...any)) (int, error) {
+	err := limits.CheckCount(uint64(count))
+	if err != nil {
+		return 0, d.Error(field, err)
+	}
+
+	err = limits.CheckSize(uint64(uncompressedSize))
+	if err != nil {
+		return 0, d.Error(field, err)
+	}
+
+	recordSize := uncompressedSize / count
+	if recordSize < minSize {
+		return 0, d.Error(field, fmt.Errorf("%d records do not fit in size %d", count, uncompressedSize))
+	}
+
+	if recordSize*count != uncompressedSize {
+		warn("%s", d.Error(field, fmt.Errorf("%d records do not match size %d", count, uncompressedSize)))
+	}
+
+	return int(recordSize), nil
+}
@@ -0,0 +1,93 @@
+package zosft
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// makeTestZosft returns a ZOSFT table whose Index1 has count1 records in block1, the other blocks are empty.
+func makeTestZosft(count1 uint32, block1 []byte, names []byte) []byte {
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	zw.Write(block1)
+	zw.Close()
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(signature)
+	buf.Write(make([]byte, 10))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, []uint32{0, count1, 0, 0})
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(len(block1)), uint32(compressed.Len())})
+	buf.Write(compressed.Bytes())
+
+	for i := 1; i < 3; i++ {
+		binary.Write(buf, binary.LittleEndian, uint16(i))
+		binary.Write(buf, binary.LittleEndian, []uint32{0, 0, 0, 0})
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(names)))
+	buf.Write(names)
+	buf.WriteString(signature)
+
+	return buf.Bytes()
+}
+
+func TestParseBlockSize(t *testing.T) {
+	names := []byte("art/icon.dds\x00art/other.dds\x00")
+
+	tests := []struct {
+		name     string
+		count1   uint32
+		block1   []byte
+		warnings int
+		wantErr  bool
+	}{
+		{
+			name:   "filled",
+			count1: 2,
+			block1: make([]byte, 8),
+		},
+		{
+			name:     "not filled",
+			count1:   2,
+			block1:   make([]byte, 9),
+			warnings: 1,
+		},
+		{
+			name:    "records too small",
+			count1:  3,
+			block1:  make([]byte, 9),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			zosftData, err := Parse(bytes.NewReader(makeTestZosft(test.count1, test.block1, names)))
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(zosftData.Warnings) != test.warnings {
+				t.Fatalf("warnings %q, want %d", zosftData.Warnings, test.warnings)
+			}
+			if len(zosftData.Index1.Block1Records) != int(test.count1) {
+				t.Fatalf("%d records, want %d", len(zosftData.Index1.Block1Records), test.count1)
+			}
+			// the fields after the block are read
+			if zosftData.OffsetFileName[13] != "art/other.dds" {
+				t.Fatalf("file names %q", zosftData.OffsetFileName)
+			}
+		})
+	}
+}